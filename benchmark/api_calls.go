@@ -0,0 +1,73 @@
+package benchmark
+
+import (
+	"bytes"
+)
+
+// maxAPICallLinePrefix is the number of bytes at the start of each log line searched for an API call marker. Request
+// bodies can make lines very long, and the markers appear near the start.
+const maxAPICallLinePrefix = 1024
+
+// apiCallMarkers are the messages the provider logs for every HTTP request it sends when TF_LOG is DEBUG or TRACE, as
+// written by the logging transports of terraform-plugin-sdk
+var apiCallMarkers = [][]byte{
+	[]byte("---[ REQUEST ]---"),
+	[]byte("Sending HTTP Request"),
+}
+
+// apiCallCounter is a writer counting the lines of terraform's log output that record an HTTP request sent by the
+// provider
+type apiCallCounter struct {
+	count int64
+	line  []byte
+}
+
+// Write counts the API calls in p, carrying over the start of a line that does not end in p
+func (c *apiCallCounter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			c.appendLine(p)
+			break
+		}
+		c.appendLine(p[:end])
+		c.countLine()
+		p = p[end+1:]
+	}
+	return n, nil
+}
+
+// Flush counts the last line if it did not end in a newline
+func (c *apiCallCounter) Flush() {
+	if len(c.line) > 0 {
+		c.countLine()
+	}
+}
+
+// appendLine adds p to the current line, up to maxAPICallLinePrefix bytes
+func (c *apiCallCounter) appendLine(p []byte) {
+	if room := maxAPICallLinePrefix - len(c.line); room > 0 {
+		c.line = append(c.line, p[:min(len(p), room)]...)
+	}
+}
+
+// countLine counts the current line if it records an API call and starts a new line
+func (c *apiCallCounter) countLine() {
+	for _, marker := range apiCallMarkers {
+		if bytes.Contains(c.line, marker) {
+			c.count++
+			break
+		}
+	}
+	c.line = c.line[:0]
+}
+
+// recordAPICalls adds the API calls counted while running a command to the current phase
+func (b *Benchmark) recordAPICalls(counter *apiCallCounter) {
+	if b.currentPhase == nil {
+		return
+	}
+	counter.Flush()
+	b.currentPhase.APICalls += counter.count
+}
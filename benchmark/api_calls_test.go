@@ -0,0 +1,30 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAPICallCounter(t *testing.T) {
+	log := strings.Join([]string{
+		"2024-05-01T12:00:45.000Z [DEBUG] provider.terraform-provider-genesyscloud: ---[ REQUEST ]---------------------------------------",
+		"2024-05-01T12:00:45.100Z [DEBUG] provider.terraform-provider-genesyscloud: ---[ RESPONSE ]--------------------------------------",
+		"2024-05-01T12:00:46.000Z [DEBUG] provider.terraform-provider-genesyscloud: Sending HTTP Request: tf_http_req_method=GET body=" + strings.Repeat("x", 2*maxAPICallLinePrefix),
+		"2024-05-01T12:00:46.100Z [DEBUG] provider.terraform-provider-genesyscloud: Received HTTP Response",
+		"Plan: 0 to add, 0 to change, 0 to destroy.",
+		"2024-05-01T12:00:47.000Z [DEBUG] provider.terraform-provider-genesyscloud: ---[ REQUEST ]---------------------------------------",
+	}, "\n")
+
+	// Write in small chunks so that markers are split across writes
+	var counter apiCallCounter
+	for start := 0; start < len(log); start += 7 {
+		if _, err := counter.Write([]byte(log[start:min(start+7, len(log))])); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	counter.Flush()
+
+	if counter.count != 3 {
+		t.Errorf("count = %d, want 3", counter.count)
+	}
+}
@@ -1,77 +1,361 @@
 package benchmark
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 // testCommitHashes tests different versions of the project by commit hash
-func (b *Benchmark) testReferences() error {
+func (b *Benchmark) testReferences() (results *Results, err error) {
 	var data []PlanDetails
+	start := time.Now()
+	b.cleanupDetails = nil
+	b.environment = b.collectEnvironment()
 
-	if err := b.initialiseTerraform(); err != nil {
-		return fmt.Errorf("terraform init failed: %v", err)
+	workspaces, err := b.prepareWorkspaces()
+	defer func() {
+		for _, ws := range workspaces {
+			if releaseErr := b.releaseWorkspace(ws); releaseErr != nil {
+				b.logMessage(LogLevelInfo, "⚠️ Failed to release workspace for %s: %v", ws, releaseErr)
+				if err == nil {
+					err = releaseErr
+				}
+			}
+		}
+	}()
+	// The final destroy normally runs before the results are built, so that they record what it left behind, but it
+	// still runs when the benchmark fails or is interrupted
+	cleanedUp := false
+	if b.FinalDestroy {
+		defer func() {
+			if cleanedUp {
+				return
+			}
+			if cleanupErr := b.finalDestroy(workspaces); cleanupErr != nil {
+				err = errors.Join(err, cleanupErr)
+			}
+		}()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop between steps when interrupted. Terraform receives the same signal, so the cleanup above still runs.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if b.SeedReference != "" {
+		seeds, err := b.seedWorkspaces(workspaces)
+		workspaces = append(workspaces, seeds...)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// Iterate through versions, testing each one
 	for i, ref := range b.References {
+		if ctx.Err() != nil {
+			return nil, errCancelled
+		}
 		b.logMessage(LogLevelInfo, "Starting benchmark for reference %s (%d/%d)", ref, i+1, len(b.References))
 
-		if err := b.makeSideload(ref); err != nil {
-			return err
+		var sha string
+		setup, err := b.makeSideload(ref, referenceLogName(ref))
+		if err == nil {
+			sha, err = b.resolveSHA()
+		}
+		if err != nil {
+			if !b.ContinueOnFailure {
+				// Checking out and building do not depend on a workspace
+				return nil, b.withLogTail(nil, run{reference: ref}, err)
+			}
+			b.logMessage(LogLevelInfo, "❌ Reference %s failed: %v", ref, err)
+			for _, ws := range workspaces {
+				data = append(data, b.failedResults(ws, run{reference: ref, sha: sha}, setup, err)...)
+			}
+			continue
+		}
+
+		for _, ws := range workspaces {
+			if ctx.Err() != nil {
+				return nil, errCancelled
+			}
+			plans, err := b.testReference(ws, run{reference: ref, sha: sha}, setup)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, plans...)
+		}
+	}
+
+	if b.CheckIdempotency {
+		b.logIdempotencySummary(data)
+	}
+
+	if b.CheckPlanEquivalence {
+		b.checkPlanEquivalence(data)
+	}
+
+	var regressionErr error
+	if b.MaxRegressionPercent > 0 {
+		regressionErr = b.checkRegressions(data)
+	}
+
+	var cleanupErr error
+	if b.FinalDestroy {
+		cleanupErr = b.finalDestroy(workspaces)
+		cleanedUp = true
+	}
+
+	results = b.newResults(start, data)
+
+	if len(b.Matrix) > 0 || len(b.TfConfigs) > 0 {
+		if err := b.writeMatrixTableToFile(data); err != nil {
+			return results, err
+		}
+	}
+
+	if b.HTMLReport {
+		if err := b.writeHTMLReportToFile(results); err != nil {
+			return results, err
+		}
+	}
+
+	if b.MarkdownReport {
+		if err := b.writeMarkdownReportToFile(results); err != nil {
+			return results, err
+		}
+	}
+
+	if err := b.writeDataToFile(results); err != nil {
+		return results, err
+	}
+
+	if err := b.appendHistory(results); err != nil {
+		return results, err
+	}
+
+	return results, errors.Join(failuresError(data), regressionErr, cleanupErr)
+}
+
+// prepareWorkspaces creates and initialises the workspace for each configuration. Without isolation terraform runs in
+// the configuration directory itself. With IsolationPerRun each configuration is copied once, and with
+// IsolationPerReference the configuration directory is only used as a template for the copy made for each reference.
+// The workspaces created so far are returned alongside any error so they can be released.
+func (b *Benchmark) prepareWorkspaces() ([]*workspace, error) {
+	var workspaces []*workspace
+	for _, config := range b.tfConfigs() {
+		ws, err := b.newWorkspace(config)
+		if err != nil {
+			return workspaces, err
 		}
 
-		if b.TfCommand != Plan {
-			if err := b.destroy(); err != nil {
-				return fmt.Errorf("destroy failed: %v", err)
+		switch b.Isolation {
+		case IsolationPerRun:
+			if ws, err = b.newTemporaryWorkspace(ws, "", nil, false); err != nil {
+				return workspaces, err
+			}
+		case IsolationNone:
+			if err := b.initialiseTerraform(ws, ws.logFilePath(initLogFileName)); err != nil {
+				return workspaces, fmt.Errorf("terraform init failed for %s: %v", ws, err)
 			}
 		}
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, nil
+}
 
-		// Time the execution of terraform command
-		b.logMessage(LogLevelInfo, "Running Terraform command for reference %s", ref)
-		start := time.Now()
-		if err := b.runTerraformCommand(ref); err != nil {
+// testReference runs every matrix cell for a reference against a configuration, applying any override for the reference
+func (b *Benchmark) testReference(base *workspace, r run, setup []Phase) (data []PlanDetails, err error) {
+	ws := base
+	override := b.referenceOverride(base.config, r.reference)
+	if override != nil || b.Isolation == IsolationPerReference {
+		// Isolated references own their state, otherwise overrides share the state of the configuration they replace
+		syncState := b.Isolation != IsolationPerReference
+		initPhase, err := b.runPhase(phaseInit, func() (err error) {
+			ws, err = b.newTemporaryWorkspace(base, referenceLogName(r.reference), override, syncState)
 			return err
+		})
+		setup = append(append([]Phase{}, setup...), initPhase)
+		if err != nil {
+			if !b.ContinueOnFailure {
+				return nil, b.withLogTail(base, r, err)
+			}
+			b.logMessage(LogLevelInfo, "❌ Reference %s failed against %s: %v", r, base, err)
+			return b.failedResults(base, r, setup, err), nil
 		}
-		end := time.Now()
 
-		duration := end.Sub(start).Seconds()
-		b.logMessage(LogLevelInfo, "Completed reference %s in %.2f seconds", ref, duration)
+		defer func() {
+			if releaseErr := b.releaseWorkspace(ws); releaseErr != nil && err == nil {
+				err = releaseErr
+			}
+		}()
+	}
 
-		// Store results
-		plan := PlanDetails{
-			Version:  ref,
-			Duration: duration,
+	for _, cell := range b.matrixCells() {
+		r.cell = cell
+		plan, err := b.measure(ws, r, setup)
+		if err != nil {
+			return nil, err
 		}
 		data = append(data, plan)
 	}
+	return data, nil
+}
+
+// measure runs every iteration of a run, recording the phases of each and the mean duration of the terraform command
+func (b *Benchmark) measure(ws *workspace, r run, setup []Phase) (PlanDetails, error) {
+	plan := PlanDetails{
+		Version: r.reference,
+		SHA:     r.sha,
+		Setup:   setup,
+		Command: redactCommand(b.measuredCommand(ws, r)),
+		Config:  ws.config.Name,
+		Cell:    r.cell.values(),
+	}
+	plan.Log = b.relativeOutputPath(ws.commandLogFilePath(run{reference: r.reference, cell: r.cell, iteration: 1}))
 
-	return b.writeDataToFile(data)
+	var total float64
+	for iteration := 1; iteration <= b.iterations(); iteration++ {
+		r.iteration = iteration
+		if b.iterations() > 1 {
+			b.logMessage(LogLevelInfo, "Starting iteration %d/%d for reference %s", iteration, b.iterations(), r)
+		}
+
+		sample, duration, err := b.measureIteration(ws, r, &plan)
+		if err != nil {
+			if !b.ContinueOnFailure {
+				return PlanDetails{}, b.withLogTail(ws, r, err)
+			}
+			b.logMessage(LogLevelInfo, "❌ Reference %s failed against %s: %v", r, ws, err)
+			plan.Failure = b.newFailure(ws, r, err)
+			break
+		}
+		plan.Samples = append(plan.Samples, sample)
+		total += duration
+	}
+	if len(plan.Samples) > 0 {
+		plan.Duration = total / float64(len(plan.Samples))
+		stats := summarise(commandDurations(plan))
+		plan.Statistics = &stats
+	}
+
+	return plan, nil
 }
 
-func (b *Benchmark) Run() (err error) {
+// measureIteration prepares the workspace state and times a single run of the terraform command, followed by any
+// checks of its outcome, which are recorded on plan
+func (b *Benchmark) measureIteration(ws *workspace, r run, plan *PlanDetails) (Sample, float64, error) {
+	sample := Sample{Iteration: r.iteration}
+
+	if ws.snapshot != "" || b.TfCommand.spec().preRun != "" {
+		prepare, err := b.runPhase(phasePrepare, func() error {
+			outputPath := b.prepareLogFilePath(ws, r)
+			if ws.snapshot != "" {
+				return b.restoreSnapshot(ws, outputPath)
+			}
+			if err := b.prepareState(ws, outputPath); err != nil {
+				return err
+			}
+			if b.TfCommand == Destroy {
+				// The measured command is a destroy too, so it is checked against the destroy guard before it runs
+				return b.guardDestroy(ws, ws.commandPreviewLogFilePath(r))
+			}
+			return nil
+		})
+		if err != nil {
+			return sample, 0, err
+		}
+		sample.Phases = append(sample.Phases, prepare)
+	}
+
+	// Time the execution of terraform command
+	b.logMessage(LogLevelInfo, "Running Terraform command for reference %s against %s", r, ws)
+	command, err := b.runPhase(phaseCommand, func() (err error) {
+		sample.Resources, err = b.runTerraformCommand(ws, r)
+		return err
+	})
+	if err != nil {
+		return sample, 0, err
+	}
+	sample.Phases = append(sample.Phases, command)
+	sample.Log = b.relativeOutputPath(ws.commandLogFilePath(r))
+	b.logMessage(LogLevelInfo, "Completed reference %s in %.2f seconds", r, command.Duration)
+
+	if b.CheckPlanEquivalence {
+		show, err := b.runPhase(phaseShow, func() (err error) {
+			plan.resourceChanges, err = b.showPlan(ws, r)
+			return err
+		})
+		if err != nil {
+			return sample, 0, err
+		}
+		sample.Phases = append(sample.Phases, show)
+	}
+
+	if b.CheckIdempotency {
+		idempotency, err := b.runPhase(phaseIdempotency, func() error {
+			details, err := b.checkIdempotency(ws, r)
+			// Keep the first non-empty plan so a perpetual diff in any iteration is reported
+			if err == nil && (plan.Idempotency == nil || plan.Idempotency.Empty) {
+				plan.Idempotency = details
+			}
+			return err
+		})
+		if err != nil {
+			return sample, 0, err
+		}
+		sample.Phases = append(sample.Phases, idempotency)
+	}
+
+	return sample, command.Duration, nil
+}
+
+// Run runs the benchmark, writing the results to a new directory in OutputDir
+func (b *Benchmark) Run() error {
+	_, err := b.RunWithResults()
+	return err
+}
+
+// RunWithResults runs the benchmark, writing the results to a new directory in OutputDir and returning them. When results were recorded
+// but the benchmark still failed, for example because of failures recorded with ContinueOnFailure or a regression
+// beyond MaxRegressionPercent, both the results and the error are returned.
+func (b *Benchmark) RunWithResults() (*Results, error) {
 	b.logMessage(LogLevelInfo, "Starting benchmark with %d references", len(b.References))
 
-	if err = b.setupConfiguration(); err != nil {
-		return fmt.Errorf("pre-config failed: %w", err)
+	if err := b.setupConfiguration(); err != nil {
+		return nil, fmt.Errorf("pre-config failed: %w", err)
 	}
 
-	if err = b.createOutputDirectories(); err != nil {
-		return fmt.Errorf("failed to create output directories: %w", err)
+	if err := b.createOutputDirectories(); err != nil {
+		return nil, fmt.Errorf("failed to create output directories: %w", err)
+	}
+
+	if err := b.pruneRuns(); err != nil {
+		return nil, fmt.Errorf("failed to prune previous runs: %w", err)
 	}
 
 	if !b.shouldSkipConfirmationOfDestructiveOperations() {
-		if err = b.confirmDestructiveOperation(); err != nil {
-			return fmt.Errorf("failed to confirm destructive operation: %w", err)
+		if err := b.confirmDestructiveOperation(); err != nil {
+			return nil, fmt.Errorf("failed to confirm destructive operation: %w", err)
 		}
 	}
 
-	if err = b.testReferences(); err != nil {
-		return fmt.Errorf("failed to test commit hashes: %w", err)
+	results, err := b.testReferences()
+	if manifestErr := b.writeManifest(); manifestErr != nil {
+		b.logMessage(LogLevelInfo, "⚠️ %v", manifestErr)
+	}
+	if err != nil {
+		return results, fmt.Errorf("failed to test commit hashes: %w", err)
 	}
 
 	b.logMessage(LogLevelInfo, "🎉 Benchmark completed successfully")
-	b.logMessage(LogLevelInfo, "📈 All results were written to the %s directory", b.OutputDir)
+	b.logMessage(LogLevelInfo, "📈 All results were written to the %s directory", b.runDir)
 
-	return
+	return results, nil
 }
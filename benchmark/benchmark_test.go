@@ -2,6 +2,7 @@ package benchmark
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -55,15 +56,20 @@ func TestBenchmark_configureDefaults(t *testing.T) {
 func TestBenchmark_configureOutputPaths(t *testing.T) {
 	b := &Benchmark{
 		OutputDir: "test-output",
+		runID:     "0a1b2c3d",
 	}
 
 	b.configureOutputPaths()
 
-	expectedLogsDir := filepath.Join(".", "test-output", "logs")
-	expectedPerformanceDir := filepath.Join(".", "test-output", "performance")
-	expectedDestroyLogPath := filepath.Join(expectedLogsDir, destroyLogFileName)
-	expectedPerformancePath := filepath.Join(expectedPerformanceDir, performanceDataFileName)
-	expectedInitLogPath := filepath.Join(expectedLogsDir, initLogFileName)
+	if filepath.Dir(b.runDir) != "test-output" || !runDirPattern.MatchString(filepath.Base(b.runDir)) || !strings.HasSuffix(b.runDir, "-0a1b2c3d") {
+		t.Errorf("runDir = %v, want a directory named after the start time and run ID in test-output", b.runDir)
+	}
+	if expected := filepath.Join("test-output", "history"); b.historyDir != expected {
+		t.Errorf("historyDir = %v, want %v", b.historyDir, expected)
+	}
+
+	expectedLogsDir := filepath.Join(b.runDir, "logs")
+	expectedPerformanceDir := filepath.Join(b.runDir, "performance")
 
 	if b.logsDir != expectedLogsDir {
 		t.Errorf("logsDir = %v, want %v", b.logsDir, expectedLogsDir)
@@ -71,15 +77,6 @@ func TestBenchmark_configureOutputPaths(t *testing.T) {
 	if b.performanceDir != expectedPerformanceDir {
 		t.Errorf("performanceDir = %v, want %v", b.performanceDir, expectedPerformanceDir)
 	}
-	if b.destroyLogFilePath != expectedDestroyLogPath {
-		t.Errorf("destroyLogFilePath = %v, want %v", b.destroyLogFilePath, expectedDestroyLogPath)
-	}
-	if b.performanceFilePath != expectedPerformancePath {
-		t.Errorf("performanceFilePath = %v, want %v", b.performanceFilePath, expectedPerformancePath)
-	}
-	if b.initLogFilePath != expectedInitLogPath {
-		t.Errorf("initLogFilePath = %v, want %v", b.initLogFilePath, expectedInitLogPath)
-	}
 }
 
 func TestBenchmark_validate(t *testing.T) {
@@ -186,6 +183,150 @@ func TestBenchmark_validate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "terraformrc file does not exist at",
 		},
+		{
+			name: "var file does not exist",
+			benchmark: &Benchmark{
+				TfCommand:           Plan,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				VarFiles:            []string{"missing.tfvars"},
+			},
+			wantErr: true,
+			errMsg:  "var file does not exist at",
+		},
+		{
+			name: "seeded destroy",
+			benchmark: &Benchmark{
+				TfCommand:           Destroy,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				SeedReference:       "main",
+			},
+			wantErr: true,
+			errMsg:  "seeded state cannot be used with",
+		},
+		{
+			name: "idempotency check for plan",
+			benchmark: &Benchmark{
+				TfCommand:           Plan,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				CheckIdempotency:    true,
+			},
+			wantErr: true,
+			errMsg:  "idempotency can only be checked for Apply benchmarks",
+		},
+		{
+			name: "resource timings for validate",
+			benchmark: &Benchmark{
+				TfCommand:           Validate,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				ResourceTimings:     true,
+			},
+			wantErr: true,
+			errMsg:  "resource timings are not supported for terraform validate",
+		},
+		{
+			name: "unknown baseline reference",
+			benchmark: &Benchmark{
+				TfCommand:           Plan,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				BaselineReference:   "main",
+			},
+			wantErr: true,
+			errMsg:  "baseline reference main is not one of the references",
+		},
+		{
+			name: "empty variable name",
+			benchmark: &Benchmark{
+				TfCommand:           Plan,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				Variables:           map[string]string{"": "value"},
+			},
+			wantErr: true,
+			errMsg:  "variable names cannot be empty",
+		},
+		{
+			name: "negative runs to keep",
+			benchmark: &Benchmark{
+				TfCommand:           Plan,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				KeepRuns:            -1,
+			},
+			wantErr: true,
+			errMsg:  "runs to keep cannot be negative",
+		},
+		{
+			name: "import without an address and ID",
+			benchmark: &Benchmark{
+				TfCommand:           Import,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				Args:                []string{"genesyscloud_user.test"},
+			},
+			wantErr: true,
+			errMsg:  "import requires the resource address and ID as the last two Args",
+		},
+		{
+			name: "regression gate with too few iterations",
+			benchmark: &Benchmark{
+				TfCommand:            Plan,
+				References:           []string{"test"},
+				ProjectPath:          "/test/path",
+				TerraformRcFilePath:  terraformrcPath,
+				TfConfigDir:          tfConfigDir,
+				MaxRegressionPercent: 10,
+				Iterations:           3,
+			},
+			wantErr: true,
+			errMsg:  "maximum regression percentage requires at least 4 iterations",
+		},
+		{
+			name: "regression gate with enough iterations",
+			benchmark: &Benchmark{
+				TfCommand:            Plan,
+				References:           []string{"test"},
+				ProjectPath:          "/test/path",
+				TerraformRcFilePath:  terraformrcPath,
+				TfConfigDir:          tfConfigDir,
+				MaxRegressionPercent: 10,
+				Iterations:           4,
+			},
+			wantErr: false,
+		},
+		{
+			name: "relative OpenMetrics path",
+			benchmark: &Benchmark{
+				TfCommand:           Plan,
+				References:          []string{"test"},
+				ProjectPath:         "/test/path",
+				TerraformRcFilePath: terraformrcPath,
+				TfConfigDir:         tfConfigDir,
+				ResultWriters:       []ResultWriter{OpenMetricsWriter{Path: "textfile/terraform_benchmark.prom"}},
+			},
+			wantErr: true,
+			errMsg:  "OpenMetrics path textfile/terraform_benchmark.prom must be absolute",
+		},
 		{
 			name: "terraform config directory does not exist",
 			benchmark: &Benchmark{
@@ -214,43 +355,65 @@ func TestBenchmark_validate(t *testing.T) {
 	}
 }
 
-func TestBenchmark_generateLogFilePath(t *testing.T) {
+func TestBenchmark_buildCommand(t *testing.T) {
 	b := &Benchmark{
-		logsDir: "/test/logs",
+		Args:      []string{"-parallelism=20", "-target=genesyscloud_user.test"},
+		Variables: map[string]string{"org": "my org", "description": `say "hello"`},
+		VarFiles:  []string{"prod.tfvars"},
 	}
 
 	tests := []struct {
-		reference string
-		expected  string
+		name        string
+		command     command
+		includeArgs bool
+		expected    []string
 	}{
 		{
-			reference: "v1.66.0",
-			expected:  filepath.Join("/test/logs", "v1_66_0.log"),
+			name:        "measured plan",
+			command:     Plan,
+			includeArgs: true,
+			expected: []string{"terraform", "plan", "-var-file=prod.tfvars", `-var=description=say "hello"`, "-var=org=my org",
+				"-parallelism=20", "-target=genesyscloud_user.test"},
 		},
 		{
-			reference: "main",
-			expected:  filepath.Join("/test/logs", "main.log"),
+			name:     "destroy without args",
+			command:  Destroy,
+			expected: []string{"terraform", "destroy", "--auto-approve", "-var-file=prod.tfvars", `-var=description=say "hello"`, "-var=org=my org"},
 		},
 		{
-			reference: "abc1234",
-			expected:  filepath.Join("/test/logs", "abc1234.log"),
-		},
-		{
-			reference: "feature.branch",
-			expected:  filepath.Join("/test/logs", "feature_branch.log"),
+			name:        "validate does not accept variables",
+			command:     Validate,
+			includeArgs: true,
+			expected:    []string{"terraform", "validate", "-parallelism=20", "-target=genesyscloud_user.test"},
 		},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.reference, func(t *testing.T) {
-			result := b.generateLogFilePath(tt.reference)
-			if result != tt.expected {
-				t.Errorf("generateLogFilePath(%s) = %v, want %v", tt.reference, result, tt.expected)
+		t.Run(tt.name, func(t *testing.T) {
+			result := b.buildCommand(&workspace{}, tt.command, tt.includeArgs)
+			if strings.Join(result, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("buildCommand() = %q, want %q", result, tt.expected)
 			}
 		})
 	}
 }
 
+func TestSafeFileName(t *testing.T) {
+	values := []string{"", "main", "v1.0", "v1_0", "v1-0", "a/b", "a_b", "a:b", "a\\b", "../main", strings.Repeat("a", 120), strings.Repeat("a", 121)}
+	names := make(map[string]string)
+	for _, value := range values {
+		name := safeFileName(value)
+		if other, ok := names[name]; ok {
+			t.Errorf("safeFileName(%q) = safeFileName(%q) = %q", value, other, name)
+		}
+		names[name] = value
+
+		if name == "" || len(name) > maxSafeFileNameLength+9 || strings.Trim(name, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789._-") != "" {
+			t.Errorf("safeFileName(%q) = %q, want a short name of safe characters", value, name)
+		}
+	}
+}
+
 func TestBenchmark_writeDataToFile(t *testing.T) {
 	// Create temporary directory for test
 	tempDir, err := os.MkdirTemp("", "benchmark_test")
@@ -269,7 +432,7 @@ func TestBenchmark_writeDataToFile(t *testing.T) {
 		{Version: "main", Duration: 11.2},
 	}
 
-	err = b.writeDataToFile(testData)
+	err = b.writeDataToFile(&Results{SchemaVersion: ResultsSchemaVersion, Results: testData})
 	if err != nil {
 		t.Fatalf("writeDataToFile() error = %v", err)
 	}
@@ -286,12 +449,17 @@ func TestBenchmark_writeDataToFile(t *testing.T) {
 		t.Fatalf("Failed to read data.json: %v", err)
 	}
 
-	var result []PlanDetails
-	err = json.Unmarshal(content, &result)
+	var results Results
+	err = json.Unmarshal(content, &results)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal JSON: %v", err)
 	}
 
+	if results.SchemaVersion != ResultsSchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", results.SchemaVersion, ResultsSchemaVersion)
+	}
+	result := results.Results
+
 	if len(result) != len(testData) {
 		t.Errorf("Expected %d records, got %d", len(testData), len(result))
 	}
@@ -337,10 +505,10 @@ func TestBenchmark_createOutputDirectories(t *testing.T) {
 		t.Fatalf("createOutputDirectories() error = %v", err)
 	}
 
-	// Verify directories were created
+	// Verify directories were created, and can be found through the latest link
 	expectedDirs := []string{
-		filepath.Join("test-output", "logs"),
-		filepath.Join("test-output", "performance"),
+		filepath.Join("test-output", "latest", "logs"),
+		filepath.Join("test-output", "latest", "performance"),
 	}
 
 	for _, dir := range expectedDirs {
@@ -349,20 +517,10 @@ func TestBenchmark_createOutputDirectories(t *testing.T) {
 		}
 	}
 
-	// Verify log files were created
-	expectedFiles := []string{
-		filepath.Join("test-output", "logs", "v1_0_0.log"),
-		filepath.Join("test-output", "logs", "main.log"),
-		filepath.Join("test-output", "logs", "feature_branch.log"),
-		filepath.Join("test-output", "logs", "destroy.log"),
-		filepath.Join("test-output", "logs", "init.log"),
-		filepath.Join("test-output", "performance", "data.json"),
-	}
-
-	for _, file := range expectedFiles {
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			t.Errorf("File %s was not created", file)
-		}
+	// Log files are left to the steps that write them, so references that never run have none
+	entries, err := os.ReadDir(filepath.Join("test-output", "latest", "logs"))
+	if err != nil || len(entries) != 0 {
+		t.Errorf("expected an empty logs directory, got %d entries (%v)", len(entries), err)
 	}
 }
 
@@ -393,7 +551,7 @@ func TestBenchmark_setupTerraformCommand(t *testing.T) {
 	defer os.Remove(outputFile.Name())
 	defer outputFile.Close()
 
-	cmd := b.setupTerraformCommand([]string{"terraform", "plan"}, outputFile, true)
+	cmd := b.setupTerraformCommand(tempDir, []string{"terraform", "plan"}, outputFile, true)
 
 	if cmd.Dir != tempDir {
 		t.Errorf("Command directory = %v, want %v", cmd.Dir, tempDir)
@@ -412,7 +570,7 @@ func TestBenchmark_setupTerraformCommand(t *testing.T) {
 	}
 
 	// Test without dev override
-	cmd = b.setupTerraformCommand([]string{"terraform", "plan"}, outputFile, false)
+	cmd = b.setupTerraformCommand(tempDir, []string{"terraform", "plan"}, outputFile, false)
 
 	// Should not have TF_CLI_CONFIG_FILE set
 	for _, env := range cmd.Env {
@@ -420,6 +578,18 @@ func TestBenchmark_setupTerraformCommand(t *testing.T) {
 			t.Error("TF_CLI_CONFIG_FILE should not be set when useDevOverride is false")
 		}
 	}
+
+	// Test with extra environment variables
+	cmd = b.setupTerraformCommand(tempDir, []string{"terraform", "plan"}, outputFile, true, "TF_LOG=DEBUG")
+
+	var foundExtra, foundConfig bool
+	for _, env := range cmd.Env {
+		foundExtra = foundExtra || env == "TF_LOG=DEBUG"
+		foundConfig = foundConfig || env == "TF_CLI_CONFIG_FILE="+terraformrcPath
+	}
+	if !foundExtra || !foundConfig {
+		t.Errorf("expected TF_LOG and TF_CLI_CONFIG_FILE to be set, got extra=%v config=%v", foundExtra, foundConfig)
+	}
 }
 
 func TestBenchmark_logMessage(t *testing.T) {
@@ -490,8 +660,15 @@ func TestCommand_String(t *testing.T) {
 		expected string
 	}{
 		{Apply, "terraform apply --auto-approve"},
+		{ApplyRefreshOnly, "terraform apply -refresh-only --auto-approve"},
+		{Destroy, "terraform destroy --auto-approve"},
+		{Import, "terraform import"},
 		{Init, "terraform init"},
 		{Plan, "terraform plan"},
+		{PlanRefreshOnly, "terraform plan -refresh-only"},
+		{ProvidersSchema, "terraform providers schema -json"},
+		{Test, "terraform test"},
+		{Validate, "terraform validate"},
 	}
 
 	for _, tt := range tests {
@@ -504,6 +681,60 @@ func TestCommand_String(t *testing.T) {
 	}
 }
 
+func TestCommand_spec(t *testing.T) {
+	tests := []struct {
+		command      command
+		mutatesState bool
+		preRun       command
+	}{
+		{Apply, true, Destroy},
+		{ApplyRefreshOnly, true, ""},
+		{Destroy, true, Apply},
+		{Import, true, stateRm},
+		{Init, false, ""},
+		{Plan, false, ""},
+		{PlanRefreshOnly, false, ""},
+		{ProvidersSchema, false, ""},
+		{Test, true, ""},
+		{Validate, false, ""},
+		{command("terraform custom"), true, Destroy},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.command), func(t *testing.T) {
+			spec := tt.command.spec()
+			if spec.mutatesState != tt.mutatesState {
+				t.Errorf("mutatesState = %v, want %v", spec.mutatesState, tt.mutatesState)
+			}
+			if spec.preRun != tt.preRun {
+				t.Errorf("preRun = %v, want %v", spec.preRun, tt.preRun)
+			}
+		})
+	}
+}
+
+func TestBenchmark_shouldSkipConfirmationOfDestructiveOperations(t *testing.T) {
+	tests := []struct {
+		name      string
+		benchmark *Benchmark
+		expected  bool
+	}{
+		{"plan", &Benchmark{TfCommand: Plan}, true},
+		{"validate", &Benchmark{TfCommand: Validate}, true},
+		{"apply", &Benchmark{TfCommand: Apply}, false},
+		{"destroy", &Benchmark{TfCommand: Destroy}, false},
+		{"apply with skip", &Benchmark{TfCommand: Apply, SkipDestroyConfirmation: true}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.benchmark.shouldSkipConfirmationOfDestructiveOperations(); result != tt.expected {
+				t.Errorf("shouldSkipConfirmationOfDestructiveOperations() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestLogLevel_String(t *testing.T) {
 	tests := []struct {
 		level    LogLevel
@@ -573,15 +804,6 @@ func TestBenchmark_Integration(t *testing.T) {
 	if b.performanceDir == "" {
 		t.Error("performanceDir was not configured")
 	}
-	if b.destroyLogFilePath == "" {
-		t.Error("destroyLogFilePath was not configured")
-	}
-	if b.performanceFilePath == "" {
-		t.Error("performanceFilePath was not configured")
-	}
-	if b.initLogFilePath == "" {
-		t.Error("initLogFilePath was not configured")
-	}
 }
 
 // Benchmark tests for performance
@@ -600,13 +822,104 @@ func BenchmarkPlanDetails_Marshal(b *testing.B) {
 	}
 }
 
-func BenchmarkBenchmark_generateLogFilePath(b *testing.B) {
-	benchmark := &Benchmark{
-		logsDir: "/test/logs",
+func TestBenchmark_measureIteration_logsEachIteration(t *testing.T) {
+	installFakeCommand(t, "terraform", `echo "$*"
+`)
+	b := &Benchmark{TfCommand: Plan, OutputDir: t.TempDir()}
+	b.configureOutputPaths()
+	if err := os.MkdirAll(b.logsDir, 0755); err != nil {
+		t.Fatal(err)
 	}
+	ws := &workspace{dir: t.TempDir(), logsDir: b.logsDir}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		benchmark.generateLogFilePath("v1.66.0")
+	for iteration := 1; iteration <= 2; iteration++ {
+		sample, _, err := b.measureIteration(ws, run{reference: "main", iteration: iteration}, &PlanDetails{})
+		if err != nil {
+			t.Fatalf("measureIteration() error = %v", err)
+		}
+		if expected := fmt.Sprintf("logs/main_%d.log", iteration); sample.Log != expected {
+			t.Errorf("sample.Log = %v, want %v", sample.Log, expected)
+		}
+	}
+
+	// Each iteration's output is kept in its own log rather than appended to the first
+	for iteration := 1; iteration <= 2; iteration++ {
+		output, err := os.ReadFile(filepath.Join(b.logsDir, fmt.Sprintf("main_%d.log", iteration)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != "plan\n" {
+			t.Errorf("log of iteration %d = %q, want %q", iteration, output, "plan\n")
+		}
+	}
+}
+
+func TestBenchmark_makeSideload_keepsSeedLogs(t *testing.T) {
+	installFakeCommand(t, "git", `echo "checked out $2"
+`)
+	installFakeCommand(t, "make", `echo "built $BUILD"
+`)
+	b := &Benchmark{ProjectPath: t.TempDir(), OutputDir: t.TempDir()}
+	b.configureOutputPaths()
+	if err := os.MkdirAll(b.logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// The seed reference is built again when it is benchmarked, which must not overwrite the seed's logs
+	t.Setenv("BUILD", "seed")
+	if _, err := b.makeSideload("main", seedLogName("main")); err != nil {
+		t.Fatalf("makeSideload() error = %v", err)
+	}
+	t.Setenv("BUILD", "benchmark")
+	if _, err := b.makeSideload("main", referenceLogName("main")); err != nil {
+		t.Fatalf("makeSideload() error = %v", err)
+	}
+
+	for name, expected := range map[string]string{
+		"seed_main_checkout.log": "checked out main\n",
+		"seed_main_build.log":    "built seed\n",
+		"main_build.log":         "built benchmark\n",
+	} {
+		output, err := os.ReadFile(filepath.Join(b.logsDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != expected {
+			t.Errorf("%s = %q, want %q", name, output, expected)
+		}
+	}
+}
+
+func TestBenchmark_measureIteration_removesImportedResource(t *testing.T) {
+	// The fake state holds the imported resource once it has been imported, and importing it again fails
+	installFakeCommand(t, "terraform", `case "$*" in
+"state list genesyscloud_user.test")
+	[ -f state ] || { echo "No state file was found!" >&2; exit 1; }
+	if [ -f imported ]; then echo genesyscloud_user.test; fi ;;
+"state rm genesyscloud_user.test") rm imported ;;
+"import genesyscloud_user.test 0a1b2c3d")
+	[ -f imported ] && { echo "Error: Resource already managed by Terraform"; exit 1; }
+	touch state imported ;;
+*) exit 1 ;;
+esac
+`)
+	b := &Benchmark{TfCommand: Import, Args: []string{"genesyscloud_user.test", "0a1b2c3d"}, OutputDir: t.TempDir()}
+	b.configureOutputPaths()
+	if err := os.MkdirAll(b.logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ws := &workspace{dir: t.TempDir(), logsDir: b.logsDir}
+
+	for iteration := 1; iteration <= 2; iteration++ {
+		sample, _, err := b.measureIteration(ws, run{reference: "main", iteration: iteration}, &PlanDetails{})
+		if err != nil {
+			t.Fatalf("measureIteration() error = %v in iteration %d", err, iteration)
+		}
+		if len(sample.Phases) != 2 || sample.Phases[0].Name != phasePrepare {
+			t.Errorf("phases = %+v, want prepare and command", sample.Phases)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(b.logsDir, "main_state-rm_2.log")); err != nil {
+		t.Errorf("state rm log was not written: %v", err)
 	}
 }
@@ -2,16 +2,24 @@ package benchmark
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 )
 
 const (
+	// maxSafeFileNameLength is the longest value safeFileName uses without shortening it
+	maxSafeFileNameLength = 100
+
 	destroyLogFileName      = "destroy.log"
 	performanceDataFileName = "data.json"
 	initLogFileName         = "init.log"
@@ -29,11 +37,15 @@ func (b *Benchmark) configureOutputPaths() {
 	if b.OutputDir == "" {
 		b.OutputDir = "output"
 	}
-	b.logsDir = filepath.Join(".", b.OutputDir, "logs")
-	b.performanceDir = filepath.Join(".", b.OutputDir, "performance")
-	b.destroyLogFilePath = filepath.Join(b.logsDir, destroyLogFileName)
-	b.performanceFilePath = filepath.Join(b.performanceDir, performanceDataFileName)
-	b.initLogFilePath = filepath.Join(b.logsDir, initLogFileName)
+	b.runDir = filepath.Join(b.OutputDir, runDirName(time.Now(), b.runID))
+	b.logsDir = filepath.Join(b.runDir, "logs")
+	b.performanceDir = filepath.Join(b.runDir, "performance")
+	b.stateDir = filepath.Join(b.runDir, "state")
+	b.plansDir = filepath.Join(b.runDir, "plans")
+	b.historyDir = b.HistoryDir
+	if b.historyDir == "" {
+		b.historyDir = filepath.Join(b.OutputDir, "history")
+	}
 }
 
 // validate the benchmark configuration
@@ -58,11 +70,67 @@ func (b *Benchmark) validate() error {
 	if _, err := os.Stat(b.TerraformRcFilePath); os.IsNotExist(err) {
 		return fmt.Errorf("terraformrc file does not exist at %s", b.TerraformRcFilePath)
 	}
-	if b.TfConfigDir == "" {
-		return errors.New("terraform config directory is required")
+	if err := b.validateTfConfigs(); err != nil {
+		return err
+	}
+	for name := range b.Variables {
+		if name == "" {
+			return errors.New("variable names cannot be empty")
+		}
+	}
+	if err := b.validateMatrix(); err != nil {
+		return err
+	}
+	if err := b.validateReferenceOverrides(); err != nil {
+		return err
+	}
+	if b.TfCommand == Import && len(b.Args) < 2 {
+		return errors.New("import requires the resource address and ID as the last two Args")
+	}
+	if b.CheckPlanEquivalence && b.TfCommand != Plan && b.TfCommand != PlanRefreshOnly {
+		return errors.New("plan equivalence can only be checked for Plan benchmarks")
+	}
+	if b.BaselineReference != "" && !slices.Contains(b.References, b.BaselineReference) {
+		return fmt.Errorf("baseline reference %s is not one of the references", b.BaselineReference)
+	}
+	if b.ResourceTimings && !b.TfCommand.spec().streamsJSON {
+		return fmt.Errorf("resource timings are not supported for %s", b.TfCommand)
+	}
+	if b.CheckIdempotency && b.TfCommand != Apply {
+		return errors.New("idempotency can only be checked for Apply benchmarks")
+	}
+	if b.MarkdownReportMaxBytes < 0 {
+		return errors.New("markdown report size limit cannot be negative")
+	}
+	if b.MaxRegressionPercent < 0 {
+		return errors.New("maximum regression percentage cannot be negative")
+	}
+	if b.Iterations < 0 {
+		return errors.New("iterations cannot be negative")
+	}
+	if b.MaxRegressionPercent > 0 && b.iterations() < minSignificantIterations {
+		// With fewer iterations no slowdown is ever significant, so the benchmark could never fail
+		return fmt.Errorf("maximum regression percentage requires at least %d iterations", minSignificantIterations)
 	}
-	if _, err := os.Stat(b.TfConfigDir); os.IsNotExist(err) {
-		return fmt.Errorf("terraform config directory does not exist at %s", b.TfConfigDir)
+	if b.KeepRuns < 0 {
+		return errors.New("runs to keep cannot be negative")
+	}
+	if b.KeepDays < 0 {
+		return errors.New("days to keep runs for cannot be negative")
+	}
+	for _, writer := range b.ResultWriters {
+		// Writers with settings of their own, such as OpenMetricsWriter, check them
+		if validator, ok := writer.(interface{ validate() error }); ok {
+			if err := validator.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	if b.MaxDestroyResources < 0 {
+		return errors.New("max destroy resources cannot be negative")
+	}
+	if b.SeedReference != "" && b.TfCommand.spec().preRun == Apply {
+		return fmt.Errorf("seeded state cannot be used with %s as it destroys the seeded infrastructure", b.TfCommand)
 	}
 
 	return nil
@@ -75,23 +143,119 @@ func (b *Benchmark) setupConfiguration() error {
 	}
 
 	b.logMessage(LogLevelInfo, "Configuring benchmark default values")
+	b.runID = newRunID()
 	b.configureDefaults()
 	b.configureOutputPaths()
 	return nil
 }
 
-// generateLogFilePath generates the path to the log file for a given reference
-func (b *Benchmark) generateLogFilePath(reference string) string {
-	filename := strings.ReplaceAll(reference, ".", "_")
-	return filepath.Join(b.logsDir, fmt.Sprintf("%s.log", filename))
+// buildCommand returns the arguments for a terraform command, adding the configured var-files and variables when the
+// command accepts them, followed by the extra arguments when includeArgs is set. Var-files are resolved for the workspace
+// the command is run in, see workspace.varFilePath. Arguments are never re-split, so values containing spaces or quotes
+// are passed through unchanged.
+func (b *Benchmark) buildCommand(ws *workspace, c command, includeArgs bool) []string {
+	args := strings.Fields(string(c))
+
+	if c.spec().acceptsVariables {
+		for _, varFile := range b.VarFiles {
+			args = append(args, "-var-file="+ws.varFilePath(varFile))
+		}
+
+		names := make([]string, 0, len(b.Variables))
+		for name := range b.Variables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			args = append(args, fmt.Sprintf("-var=%s=%s", name, b.Variables[name]))
+		}
+	}
+
+	if includeArgs {
+		args = append(args, b.Args...)
+	}
+	return args
 }
 
-// setupTerraformCommand creates and configures a terraform command with proper environment
-func (b *Benchmark) setupTerraformCommand(command []string, outputFile *os.File, useDevOverride bool) *exec.Cmd {
+// logFileName returns the name of the log file with the given base name, which must already be safe, see safeFileName
+func logFileName(name string) string {
+	return fmt.Sprintf("%s.log", name)
+}
+
+// safeFileName returns a name for value that is safe to use in a file name on any platform. Values made only of
+// letters, digits, dots and hyphens, such as most tags and SHAs, are used as they are. Other characters, e.g. the
+// slash in "feature/fast-plan", are replaced by underscores and a hash of value is appended, so that no two values
+// share a name and names containing an underscore never come from a value.
+func safeFileName(value string) string {
+	safe := true
+	name := []byte(value)
+	for i, c := range name {
+		if !isSafeFileNameByte(c) {
+			name[i] = '_'
+			safe = false
+		}
+	}
+	if safe && value != "" && len(value) <= maxSafeFileNameLength {
+		return value
+	}
+
+	if len(name) > maxSafeFileNameLength {
+		name = name[:maxSafeFileNameLength]
+	}
+	hash := sha256.Sum256([]byte(value))
+	return string(name) + "-" + hex.EncodeToString(hash[:4])
+}
+
+// isSafeFileNameByte reports whether c can be used in a file name unchanged
+func isSafeFileNameByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '.' || c == '-'
+}
+
+// measuredCommand returns the full command line of the measured terraform command for a run. Args come last, after
+// every flag the benchmark adds, as they may end with positional arguments such as the address and ID for Import.
+func (b *Benchmark) measuredCommand(ws *workspace, r run) []string {
+	command := append(b.buildCommand(ws, b.TfCommand, false), r.cell.args()...)
+	if b.CheckPlanEquivalence {
+		command = append(command, "-out="+b.planFilePath(ws, r))
+	}
+	if b.ResourceTimings {
+		command = append(command, "-json")
+	}
+	return append(command, b.Args...)
+}
+
+// relativeOutputPath returns path relative to the run directory with forward slashes, or path itself if it is outside
+// the run directory
+func (b *Benchmark) relativeOutputPath(path string) string {
+	relative, err := filepath.Rel(b.runDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(relative)
+}
+
+// baselineReference returns the reference other references are compared against
+func (b *Benchmark) baselineReference() string {
+	if b.BaselineReference != "" {
+		return b.BaselineReference
+	}
+	if len(b.References) == 0 {
+		return ""
+	}
+	return b.References[0]
+}
+
+// setupTerraformCommand creates and configures a terraform command run in dir with proper environment, adding any
+// extra environment variables given
+func (b *Benchmark) setupTerraformCommand(dir string, command []string, outputFile *os.File, useDevOverride bool, extraEnv ...string) *exec.Cmd {
 	cmd := exec.Command(command[0], command[1:]...)
 	cmd.Stdout = outputFile
 	cmd.Stderr = outputFile
-	cmd.Dir = b.TfConfigDir
+	cmd.Dir = dir
+
+	if len(extraEnv) > 0 {
+		cmd.Env = append(os.Environ(), extraEnv...)
+	}
 
 	if !useDevOverride {
 		return cmd
@@ -104,7 +268,10 @@ func (b *Benchmark) setupTerraformCommand(command []string, outputFile *os.File,
 
 	// Set TF_CLI_CONFIG_FILE to b.TerraformRcFilePath
 	b.logMessage(LogLevelDebug, "Setting TF_CLI_CONFIG_FILE to "+b.TerraformRcFilePath)
-	env := os.Environ()
+	env := cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
 	env = append(env, "TF_CLI_CONFIG_FILE="+b.TerraformRcFilePath)
 	cmd.Env = env
 
@@ -124,8 +291,8 @@ func (b *Benchmark) logMessage(level LogLevel, format string, args ...interface{
 
 // confirmDestructiveOperation prompts the user for confirmation before destructive operations
 func (b *Benchmark) confirmDestructiveOperation() error {
-	fmt.Printf("\n⚠️  WARNING: About to run destructive terraform operation\n")
-	fmt.Printf("This will destroy any existing Terraform state.\n")
+	fmt.Printf("\n⚠️  WARNING: About to run destructive terraform operation: %s\n", b.TfCommand)
+	fmt.Printf("This may modify or destroy existing infrastructure and Terraform state.\n")
 	fmt.Printf("Are you sure you want to continue? (yes/no): ")
 
 	reader := bufio.NewReader(os.Stdin)
@@ -143,6 +310,14 @@ func (b *Benchmark) confirmDestructiveOperation() error {
 	return nil
 }
 
+// shouldSkipConfirmationOfDestructiveOperations reports whether the confirmation prompt can be skipped, which is the
+// case when the user opted out or nothing the benchmark runs can change state
 func (b *Benchmark) shouldSkipConfirmationOfDestructiveOperations() bool {
-	return b.SkipDestroyConfirmation || b.TfCommand == Plan
+	return b.SkipDestroyConfirmation || !b.mutatesState()
+}
+
+// mutatesState reports whether the benchmark can change infrastructure or state, either through the measured command
+// or by seeding and destroying state around it
+func (b *Benchmark) mutatesState() bool {
+	return b.TfCommand.spec().mutatesState || b.SeedReference != "" || b.FinalDestroy
 }
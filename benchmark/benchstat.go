@@ -0,0 +1,124 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// BenchstatWriter writes the measured command of every iteration in the Go benchmark text format to benchstat.txt,
+// so results can be compared with benchstat and other Go performance tooling
+type BenchstatWriter struct{}
+
+// FileName returns the name of the benchstat results file
+func (BenchstatWriter) FileName() string {
+	return "benchstat.txt"
+}
+
+// Write writes one benchmark line per iteration, preceded by goos, goarch, host and cpu configuration lines where
+// known. A commit configuration line is written whenever the SHA changes, so it applies to the results of that
+// reference.
+func (BenchstatWriter) Write(w io.Writer, results *Results) error {
+	env := results.Environment
+	for _, config := range [][2]string{{"goos", env.OS}, {"goarch", env.Arch}, {"host", env.Hostname}, {"cpu", env.CPU}} {
+		if config[1] == "" {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", config[0], config[1]); err != nil {
+			return err
+		}
+	}
+
+	commit := ""
+	for _, plan := range results.Results {
+		// A failed result's timings are incomplete and would be compared as if the command had completed
+		if plan.Failure != nil {
+			continue
+		}
+		if plan.SHA != "" && plan.SHA != commit {
+			commit = plan.SHA
+			if _, err := fmt.Fprintf(w, "commit: %s\n", commit); err != nil {
+				return err
+			}
+		}
+
+		name := benchstatName(plan)
+		for _, phase := range commandPhases(plan) {
+			line := fmt.Sprintf("%s 1 %d ns/op", name, secondsToNanoseconds(phase.Duration))
+			if phase.UserCPU > 0 || phase.SystemCPU > 0 {
+				line += fmt.Sprintf(" %d user-ns/op %d sys-ns/op", secondsToNanoseconds(phase.UserCPU), secondsToNanoseconds(phase.SystemCPU))
+			}
+			if phase.PeakRSSBytes > 0 {
+				line += fmt.Sprintf(" %d peak-rss-bytes", phase.PeakRSSBytes)
+			}
+			if phase.APICalls > 0 {
+				line += fmt.Sprintf(" %d api-calls", phase.APICalls)
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// commandPhases returns the measured command phase of every iteration. Results recorded before samples were, which
+// have none and did not fail, are reported with their mean duration.
+func commandPhases(plan PlanDetails) []Phase {
+	var phases []Phase
+	for _, sample := range plan.Samples {
+		for _, phase := range sample.Phases {
+			if phase.Name == phaseCommand {
+				phases = append(phases, phase)
+			}
+		}
+	}
+	if len(phases) == 0 && len(plan.Samples) == 0 && plan.Failure == nil {
+		phases = append(phases, Phase{Name: phaseCommand, Duration: plan.Duration})
+	}
+	return phases
+}
+
+// benchstatName returns the benchmark name of a result, e.g. BenchmarkPlan/ref=main/config=small/parallelism=10
+func benchstatName(plan PlanDetails) string {
+	parts := []string{"Benchmark" + commandName(plan.Command), "ref=" + benchstatValue(plan.Version)}
+	if plan.Config != "" {
+		parts = append(parts, "config="+benchstatValue(plan.Config))
+	}
+	for _, value := range strings.Split(formatCell(plan.Cell), ",") {
+		if value != "" {
+			parts = append(parts, benchstatValue(value))
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// benchstatValue makes a value safe to use in a benchmark name, which cannot contain whitespace and uses slashes to
+// separate sub-benchmarks
+func benchstatValue(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\t', '\n', '\r', '/':
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// commandName returns the name of the known command that the command line starts with, or "Custom"
+func commandName(commandLine []string) string {
+	name, matched := "Custom", 0
+	for c, spec := range commandSpecs {
+		fields := strings.Fields(string(c))
+		if len(fields) > matched && len(fields) <= len(commandLine) && slices.Equal(fields, commandLine[:len(fields)]) {
+			name, matched = spec.name, len(fields)
+		}
+	}
+	return name
+}
+
+// secondsToNanoseconds converts a duration in seconds to whole nanoseconds
+func secondsToNanoseconds(seconds float64) int64 {
+	return int64(seconds * 1e9)
+}
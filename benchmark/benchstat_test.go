@@ -0,0 +1,95 @@
+package benchmark
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBenchstatWriter_Write(t *testing.T) {
+	data := testResults()
+	data[0].Command = []string{"terraform", "plan", "-parallelism=10"}
+	data = append(data, PlanDetails{Version: "broken", Command: []string{"terraform", "plan"}, Failure: &Failure{Phase: phaseCommand, Iteration: 1}})
+
+	var buf bytes.Buffer
+	env := Environment{OS: "linux", Arch: "amd64", Hostname: "perf-runner-1", CPU: "AMD EPYC 7B13"}
+	if err := (BenchstatWriter{}).Write(&buf, &Results{Environment: env, Results: data}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"goos: linux\ngoarch: amd64\nhost: perf-runner-1\ncpu: AMD EPYC 7B13\ncommit: abc1234\n",
+		"BenchmarkPlan/ref=main/config=small-org/log=DEBUG/parallelism=10 1 2500000000 ns/op 1250000000 user-ns/op 500000000 sys-ns/op 1024 peak-rss-bytes 42 api-calls\n",
+		"BenchmarkPlan/ref=main/config=small-org/log=DEBUG/parallelism=10 1 2000000000 ns/op\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	if strings.Contains(output, "checkout") {
+		t.Errorf("expected only command phases to be written, got:\n%s", output)
+	}
+	if strings.Contains(output, "ref=broken") {
+		t.Errorf("expected failed results to be skipped, got:\n%s", output)
+	}
+}
+
+func TestCommandPhases(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     PlanDetails
+		expected []float64
+	}{
+		{name: "samples", plan: PlanDetails{Duration: 2, Samples: samplesOf(1, 3)}, expected: []float64{1, 3}},
+		{name: "recorded before samples", plan: PlanDetails{Duration: 2}, expected: []float64{2}},
+		{name: "failed without samples", plan: PlanDetails{Failure: &Failure{Phase: phaseInit}}, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := commandDurations(tt.plan); !slices.Equal(result, tt.expected) {
+				t.Errorf("commandDurations() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBenchstatName(t *testing.T) {
+	tests := []struct {
+		name     string
+		plan     PlanDetails
+		expected string
+	}{
+		{
+			name:     "plan",
+			plan:     PlanDetails{Version: "main", Command: []string{"terraform", "plan"}},
+			expected: "BenchmarkPlan/ref=main",
+		},
+		{
+			name:     "refresh only plan",
+			plan:     PlanDetails{Version: "v1.66.0", Command: []string{"terraform", "plan", "-refresh-only", "-var=a=b"}},
+			expected: "BenchmarkPlanRefreshOnly/ref=v1.66.0",
+		},
+		{
+			name:     "unsafe reference",
+			plan:     PlanDetails{Version: "feature/fast plan", Command: []string{"terraform", "apply", "--auto-approve"}},
+			expected: "BenchmarkApply/ref=feature_fast_plan",
+		},
+		{
+			name:     "custom command",
+			plan:     PlanDetails{Version: "main", Command: []string{"terraform", "refresh"}},
+			expected: "BenchmarkCustom/ref=main",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := benchstatName(tt.plan); result != tt.expected {
+				t.Errorf("benchstatName() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package benchmark
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMinSegment   = 3
+	defaultPermutations = 199
+)
+
+// ChangePointOptions tunes DetectChangePoints
+type ChangePointOptions struct {
+	// MinSegment is the fewest commits either side of a change point (Defaults to 3)
+	MinSegment int
+
+	// Permutations is the number of random permutations used to test the significance of a change point (Defaults to 199)
+	Permutations int
+
+	// Significance is the p-value below which a change point is reported (Defaults to 0.05)
+	Significance float64
+}
+
+// ChangePoint is a commit at which the duration of a scenario shifted
+type ChangePoint struct {
+	// Command and Scenario identify the series the change was found in
+	Command  string `json:"command"`
+	Scenario string `json:"scenario"`
+
+	// SHA, Ref and Time identify the first commit after the change
+	SHA  string    `json:"sha"`
+	Ref  string    `json:"ref"`
+	Time time.Time `json:"time"`
+
+	// Before and After are the median durations in seconds of the commits either side of the change, up to the
+	// neighbouring change points
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+
+	// Delta is the percentage change from Before to After
+	Delta float64 `json:"delta"`
+
+	// P is the p-value of the permutation test
+	P float64 `json:"p"`
+}
+
+// Regression is true when the scenario got slower at the change point
+func (c ChangePoint) Regression() bool {
+	return c.After > c.Before
+}
+
+// commitPoint is the median duration of a scenario at a commit
+type commitPoint struct {
+	sha   string
+	ref   string
+	time  time.Time
+	value float64
+}
+
+// DetectChangePoints finds the commits at which the duration of each command and scenario in the history shifted. Each
+// series holds the median duration of every commit, ordered by when the commit was first benchmarked, so entries are
+// best filtered to a single branch first. Results whose steps failed are ignored. Change points are found with
+// E-divisive: the series is split recursively where the energy distance between the two sides is greatest, for as long
+// as the split is significant under a permutation test. The permutations are seeded, so the same history always gives the same
+// change points.
+func DetectChangePoints(entries []HistoryEntry, opts ChangePointOptions) []ChangePoint {
+	if opts.MinSegment < 1 {
+		opts.MinSegment = defaultMinSegment
+	}
+	if opts.Permutations < 1 {
+		opts.Permutations = defaultPermutations
+	}
+	if opts.Significance <= 0 {
+		opts.Significance = significanceLevel
+	}
+
+	type seriesKey struct{ command, scenario string }
+	var keys []seriesKey
+	series := make(map[seriesKey][]HistoryEntry)
+	for _, entry := range entries {
+		// Only failed steps leave incomplete timings. Regressions in particular are the shifts being looked for.
+		if entry.Result.Failure != nil {
+			continue
+		}
+		key := seriesKey{commandName(entry.Result.Command), entry.Scenario()}
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], entry)
+	}
+
+	var changePoints []ChangePoint
+	for _, key := range keys {
+		points := commitPoints(series[key])
+		values := make([]float64, len(points))
+		for i, point := range points {
+			values[i] = point.value
+		}
+
+		splits := eDivisive(values, opts)
+		for i, split := range splits {
+			start, end := 0, len(values)
+			if i > 0 {
+				start = splits[i-1].index
+			}
+			if i < len(splits)-1 {
+				end = splits[i+1].index
+			}
+
+			before := summarise(values[start:split.index]).Median
+			after := summarise(values[split.index:end]).Median
+			changePoint := ChangePoint{
+				Command:  key.command,
+				Scenario: key.scenario,
+				SHA:      points[split.index].sha,
+				Ref:      points[split.index].ref,
+				Time:     points[split.index].time,
+				Before:   before,
+				After:    after,
+				P:        split.p,
+			}
+			if before > 0 {
+				changePoint.Delta = (after - before) / before * 100
+			}
+			changePoints = append(changePoints, changePoint)
+		}
+	}
+	return changePoints
+}
+
+// commitPoints returns the median duration of every commit in a series, ordered by when the commit was first
+// benchmarked. Results without a SHA are treated as a commit of their own.
+func commitPoints(entries []HistoryEntry) []commitPoint {
+	entries = slices.Clone(entries)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	var points []commitPoint
+	durations := make(map[string][]float64)
+	index := make(map[string]int)
+	for i, entry := range entries {
+		sha := entry.Result.SHA
+		key := sha
+		if key == "" {
+			key = entry.RunID + "/" + strconv.Itoa(i)
+		}
+		if _, ok := index[key]; !ok {
+			index[key] = len(points)
+			points = append(points, commitPoint{sha: sha, ref: entry.Result.Version, time: entry.Time})
+		}
+
+		if d := commandDurations(entry.Result); len(d) > 0 {
+			durations[key] = append(durations[key], d...)
+		} else {
+			durations[key] = append(durations[key], entry.Result.Duration)
+		}
+	}
+
+	for key, i := range index {
+		points[i].value = summarise(durations[key]).Median
+	}
+	return points
+}
+
+// split is a significant change point found by eDivisive, at the first value after the change
+type split struct {
+	index int
+	p     float64
+}
+
+// eDivisive returns the significant change points of values in order
+func eDivisive(values []float64, opts ChangePointOptions) []split {
+	rng := rand.New(rand.NewPCG(1, 2))
+	segments := [][2]int{{0, len(values)}}
+	var splits []split
+
+	for {
+		// Find the segment with the strongest candidate split
+		best, bestSegment, bestQ := -1, -1, 0.0
+		for i, segment := range segments {
+			if index, q := bestSplit(values[segment[0]:segment[1]], opts.MinSegment); index >= 0 && q > bestQ {
+				best, bestSegment, bestQ = segment[0]+index, i, q
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		// Test it against random permutations of the segment
+		segment := segments[bestSegment]
+		permuted := slices.Clone(values[segment[0]:segment[1]])
+		exceeded := 0
+		for range opts.Permutations {
+			rng.Shuffle(len(permuted), func(i, j int) { permuted[i], permuted[j] = permuted[j], permuted[i] })
+			if _, q := bestSplit(permuted, opts.MinSegment); q >= bestQ {
+				exceeded++
+			}
+		}
+		p := float64(exceeded+1) / float64(opts.Permutations+1)
+		if p >= opts.Significance {
+			break
+		}
+
+		splits = append(splits, split{index: best, p: p})
+		segments = append(segments[:bestSegment], append([][2]int{{segment[0], best}, {best, segment[1]}}, segments[bestSegment+1:]...)...)
+	}
+
+	sort.Slice(splits, func(i, j int) bool { return splits[i].index < splits[j].index })
+	return splits
+}
+
+// bestSplit returns the index that maximises the scaled energy distance between the values before and after it, and
+// that distance, or -1 if values is too short to split
+func bestSplit(values []float64, minSegment int) (int, float64) {
+	n := len(values)
+	if n < 2*minSegment {
+		return -1, 0
+	}
+
+	// prefix[i][j] is the sum of |values[a]-values[b]| for a < i and b < j
+	prefix := make([][]float64, n+1)
+	for i := range prefix {
+		prefix[i] = make([]float64, n+1)
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= n; j++ {
+			prefix[i][j] = math.Abs(values[i-1]-values[j-1]) + prefix[i-1][j] + prefix[i][j-1] - prefix[i-1][j-1]
+		}
+	}
+	sum := func(rowStart, rowEnd, colStart, colEnd int) float64 {
+		return prefix[rowEnd][colEnd] - prefix[rowStart][colEnd] - prefix[rowEnd][colStart] + prefix[rowStart][colStart]
+	}
+
+	best, bestQ := -1, math.Inf(-1)
+	for tau := minSegment; tau <= n-minSegment; tau++ {
+		m, k := float64(tau), float64(n-tau)
+		between := 2 * sum(0, tau, tau, n) / (m * k)
+		var withinBefore, withinAfter float64
+		if tau > 1 {
+			withinBefore = sum(0, tau, 0, tau) / (m * (m - 1))
+		}
+		if n-tau > 1 {
+			withinAfter = sum(tau, n, tau, n) / (k * (k - 1))
+		}
+
+		if q := m * k / (m + k) * (between - withinBefore - withinAfter); q > bestQ {
+			best, bestQ = tau, q
+		}
+	}
+	return best, bestQ
+}
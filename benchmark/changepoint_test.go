@@ -0,0 +1,107 @@
+package benchmark
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// historyOf returns a history entry per duration, each at a new commit a day apart
+func historyOf(scenario string, durations ...float64) []HistoryEntry {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := make([]HistoryEntry, len(durations))
+	for i, duration := range durations {
+		entries[i] = HistoryEntry{
+			RunID: fmt.Sprintf("run%d", i),
+			Time:  start.AddDate(0, 0, i),
+			Result: PlanDetails{
+				Version:  "main",
+				SHA:      fmt.Sprintf("%040d", i),
+				Command:  []string{"terraform", "plan"},
+				Config:   scenario,
+				Duration: duration,
+			},
+		}
+	}
+	return entries
+}
+
+func TestDetectChangePoints(t *testing.T) {
+	t.Run("step regression", func(t *testing.T) {
+		entries := historyOf("small", 10, 10.2, 9.9, 10.1, 10, 9.8, 12.1, 12, 12.3, 11.9, 12.2, 12)
+		changePoints := DetectChangePoints(entries, ChangePointOptions{})
+		if len(changePoints) != 1 {
+			t.Fatalf("DetectChangePoints() = %+v, want one change point", changePoints)
+		}
+
+		changePoint := changePoints[0]
+		if changePoint.SHA != entries[6].Result.SHA || changePoint.Command != "Plan" || changePoint.Scenario != "small" {
+			t.Errorf("change point at %s %s %s, want %s Plan small", changePoint.SHA, changePoint.Command, changePoint.Scenario, entries[6].Result.SHA)
+		}
+		if !changePoint.Regression() || fmt.Sprintf("%.1f", changePoint.Delta) != "20.5" {
+			t.Errorf("change point delta = %.1f%% (regression %t), want a 20.5%% regression", changePoint.Delta, changePoint.Regression())
+		}
+		if changePoint.P >= significanceLevel {
+			t.Errorf("change point p = %.3f, want < %.2f", changePoint.P, significanceLevel)
+		}
+	})
+
+	t.Run("regression then improvement", func(t *testing.T) {
+		entries := historyOf("small", 10, 10.1, 9.9, 10, 10.2, 15, 15.2, 14.9, 15, 15.1, 8, 8.1, 7.9, 8, 8.2)
+		changePoints := DetectChangePoints(entries, ChangePointOptions{})
+		if len(changePoints) != 2 {
+			t.Fatalf("DetectChangePoints() = %+v, want two change points", changePoints)
+		}
+		if changePoints[0].SHA != entries[5].Result.SHA || !changePoints[0].Regression() {
+			t.Errorf("first change point = %+v, want a regression at commit 5", changePoints[0])
+		}
+		if changePoints[1].SHA != entries[10].Result.SHA || changePoints[1].Regression() {
+			t.Errorf("second change point = %+v, want an improvement at commit 10", changePoints[1])
+		}
+	})
+
+	t.Run("noise only", func(t *testing.T) {
+		entries := historyOf("small", 10, 10.3, 9.8, 10.1, 9.9, 10.2, 10, 9.7, 10.1, 10.2, 9.9, 10)
+		if changePoints := DetectChangePoints(entries, ChangePointOptions{}); len(changePoints) != 0 {
+			t.Errorf("DetectChangePoints() = %+v, want none", changePoints)
+		}
+	})
+
+	t.Run("too few commits", func(t *testing.T) {
+		entries := historyOf("small", 10, 10, 20, 20)
+		if changePoints := DetectChangePoints(entries, ChangePointOptions{}); len(changePoints) != 0 {
+			t.Errorf("DetectChangePoints() = %+v, want none", changePoints)
+		}
+	})
+
+	t.Run("series are separated and failures ignored", func(t *testing.T) {
+		entries := append(historyOf("small", 10, 10, 10, 10, 10, 10, 10, 10), historyOf("large", 50, 50.5, 49.5, 50, 80, 80.5, 79.5, 80)...)
+		entries = append(entries, HistoryEntry{
+			Time:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+			Result: PlanDetails{Command: []string{"terraform", "plan"}, Config: "small", Duration: 100, Failure: &Failure{Phase: phaseCommand}},
+		})
+		changePoints := DetectChangePoints(entries, ChangePointOptions{})
+		if len(changePoints) != 1 || changePoints[0].Scenario != "large" {
+			t.Errorf("DetectChangePoints() = %+v, want one change point in large", changePoints)
+		}
+	})
+
+	t.Run("results flagged as regressions are kept", func(t *testing.T) {
+		entries := historyOf("small", 10, 10.2, 9.9, 10.1, 10, 9.8, 12.1, 12, 12.3, 11.9, 12.2, 12)
+		for i := 6; i < len(entries); i++ {
+			entries[i].Result.Regression = &RegressionDetails{Baseline: "v1.66.0", Delta: 20, P: 0.01}
+		}
+		changePoints := DetectChangePoints(entries, ChangePointOptions{})
+		if len(changePoints) != 1 || changePoints[0].SHA != entries[6].Result.SHA {
+			t.Errorf("DetectChangePoints() = %+v, want one change point at commit 6", changePoints)
+		}
+	})
+
+	t.Run("repeated runs of a commit are pooled", func(t *testing.T) {
+		entries := historyOf("small", 10, 10, 10, 10, 20, 20, 20, 20)
+		entries = append(entries, entries...)
+		if changePoints := DetectChangePoints(entries, ChangePointOptions{}); len(changePoints) != 1 || changePoints[0].SHA != entries[4].Result.SHA {
+			t.Errorf("DetectChangePoints() = %+v, want one change point at commit 4", changePoints)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	cleanupLogFileName  = "cleanup.log"
+	cleanupDataFileName = "cleanup.json"
+)
+
+var errCancelled = errors.New("benchmark cancelled")
+
+// finalDestroy destroys everything in the state of each initialised workspace and verifies that the state is empty,
+// logging any resources that were left behind. The outcome for every workspace is written to the performance directory.
+// Workspaces already cleaned up when they were released are reported alongside them.
+func (b *Benchmark) finalDestroy(workspaces []*workspace) error {
+	var errs []error
+
+	for _, ws := range workspaces {
+		if !ws.initialised || ws.cleanedUp {
+			continue
+		}
+
+		b.logMessage(LogLevelInfo, "🧹 Running final destroy for %s", ws)
+		if _, err := b.cleanUp(ws); err != nil {
+			errs = append(errs, fmt.Errorf("final destroy failed for %s: %w", ws, err))
+		}
+	}
+
+	if err := b.writeCleanupToFile(b.cleanupDetails); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// cleanUp destroys everything in the workspace's state, lists whatever remains and records the outcome
+func (b *Benchmark) cleanUp(ws *workspace) (details CleanupDetails, err error) {
+	details = CleanupDetails{Config: ws.config.Name, Remaining: []string{}}
+	defer func() {
+		b.cleanupDetails = append(b.cleanupDetails, details)
+	}()
+
+	if err := b.destroy(ws, ws.destroyLogFilePath()); err != nil {
+		details.Error = err.Error()
+		return details, appendLogTail(err, ws.destroyLogFilePath())
+	}
+	ws.cleanedUp = true
+
+	remaining, err := b.listState(ws)
+	if err != nil {
+		details.Error = err.Error()
+		return details, err
+	}
+	details.Remaining = remaining
+
+	if len(remaining) > 0 {
+		b.logMessage(LogLevelInfo, "⚠️ %d resources were left behind in %s: %s", len(remaining), ws, strings.Join(remaining, ", "))
+		err := fmt.Errorf("%d resources were left in state after destroy", len(remaining))
+		details.Error = err.Error()
+		return details, err
+	}
+
+	b.logMessage(LogLevelInfo, "🧹 State is empty for %s", ws)
+	return details, nil
+}
+
+// listState returns the addresses of the resources in the workspace's state
+func (b *Benchmark) listState(ws *workspace) ([]string, error) {
+	logFile, err := os.OpenFile(ws.logFilePath(cleanupLogFileName), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer logFile.Close()
+
+	var stdout bytes.Buffer
+	cmd := b.setupTerraformCommand(ws.dir, []string{"terraform", "state", "list"}, logFile, true, ws.env...)
+	cmd.Stdout = &stdout
+	if err := b.runCommand(cmd); err != nil {
+		return nil, fmt.Errorf("terraform state list failed: %v", err)
+	}
+
+	return parseStateList(stdout.String()), nil
+}
+
+// parseStateList returns the resource addresses printed by terraform state list
+func parseStateList(output string) []string {
+	addresses := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if address := strings.TrimSpace(line); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+// writeCleanupToFile writes the final destroy outcome to a JSON file in the performance directory
+func (b *Benchmark) writeCleanupToFile(details []CleanupDetails) error {
+	cleanupFilePath := filepath.Join(b.performanceDir, cleanupDataFileName)
+	b.logMessage(LogLevelInfo, "Writing cleanup results to %s", cleanupFilePath)
+
+	jsonData, err := json.MarshalIndent(details, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+
+	return os.WriteFile(cleanupFilePath, jsonData, 0644)
+}
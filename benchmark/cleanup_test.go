@@ -0,0 +1,110 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseStateList(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected []string
+	}{
+		{name: "empty state", output: "", expected: []string{}},
+		{
+			name:     "leftover resources",
+			output:   "genesyscloud_user.alice\nmodule.queues.genesyscloud_routing_queue.q[\"support\"]\n\n",
+			expected: []string{"genesyscloud_user.alice", `module.queues.genesyscloud_routing_queue.q["support"]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := parseStateList(tt.output)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("parseStateList() = %v, want %v", result, tt.expected)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("parseStateList()[%d] = %v, want %v", i, result[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBenchmark_mutatesState(t *testing.T) {
+	tests := []struct {
+		name      string
+		benchmark *Benchmark
+		expected  bool
+	}{
+		{"plan", &Benchmark{TfCommand: Plan}, false},
+		{"apply", &Benchmark{TfCommand: Apply}, true},
+		{"plan with final destroy", &Benchmark{TfCommand: Plan, FinalDestroy: true}, true},
+		{"plan with seed", &Benchmark{TfCommand: Plan, SeedReference: "main"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.benchmark.mutatesState(); result != tt.expected {
+				t.Errorf("mutatesState() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBenchmark_writeCleanupToFile(t *testing.T) {
+	b := &Benchmark{performanceDir: t.TempDir()}
+
+	details := []CleanupDetails{
+		{Config: "small", Remaining: []string{}},
+		{Config: "large", Remaining: []string{"genesyscloud_user.alice"}, Error: "1 resources were left in state after destroy"},
+	}
+	if err := b.writeCleanupToFile(details); err != nil {
+		t.Fatalf("writeCleanupToFile() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(b.performanceDir, cleanupDataFileName))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", cleanupDataFileName, err)
+	}
+
+	var result []CleanupDetails
+	if err := json.Unmarshal(content, &result); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+	if len(result) != 2 || result[1].Remaining[0] != "genesyscloud_user.alice" || result[1].Error == "" {
+		t.Errorf("cleanup results = %+v", result)
+	}
+}
+
+func TestBenchmark_finalDestroy_recordedInResults(t *testing.T) {
+	// The destroy leaves a resource behind
+	installFakeCommand(t, "terraform", `case "$1" in
+state) echo genesyscloud_user.alice ;;
+esac
+`)
+	b := &Benchmark{TfCommand: Apply, FinalDestroy: true, OutputDir: t.TempDir()}
+	b.configureOutputPaths()
+	for _, dir := range []string{b.logsDir, b.performanceDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ws := &workspace{dir: t.TempDir(), logsDir: b.logsDir, initialised: true}
+
+	if err := b.finalDestroy([]*workspace{ws}); err == nil {
+		t.Error("finalDestroy() should fail when resources are left in state")
+	}
+
+	// The results are built after the final destroy, so they record what it left behind
+	results := b.newResults(time.Now(), nil)
+	if len(results.Cleanup) != 1 || len(results.Cleanup[0].Remaining) != 1 || results.Cleanup[0].Remaining[0] != "genesyscloud_user.alice" {
+		t.Errorf("results.Cleanup = %+v, want genesyscloud_user.alice remaining", results.Cleanup)
+	}
+}
@@ -0,0 +1,246 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formats accepted by WriteComparison
+const (
+	ComparisonText     = "text"
+	ComparisonMarkdown = "markdown"
+	ComparisonJSON     = "json"
+)
+
+// CompareRuns compares each result of new with the matching result of old: the result of the same command and scenario
+// at the same reference or, failing that, at the same commit. Results recorded before the command line was, e.g. in
+// legacy documents, are matched on the reference or commit and scenario alone. Results without a match, or that
+// failed, are skipped.
+func CompareRuns(old, new *Results) []Comparison {
+	byRef, bySHA := newRunIndex(), newRunIndex()
+	for _, plan := range old.Results {
+		if plan.Failure != nil {
+			continue
+		}
+		byRef.add(plan, plan.Version)
+		if plan.SHA != "" {
+			bySHA.add(plan, plan.SHA)
+		}
+	}
+
+	var comparisons []Comparison
+	for _, plan := range new.Results {
+		if plan.Failure != nil {
+			continue
+		}
+		baseline, ok := byRef.find(plan, plan.Version)
+		if !ok && plan.SHA != "" {
+			baseline, ok = bySHA.find(plan, plan.SHA)
+		}
+		if ok {
+			comparisons = append(comparisons, newComparison(baseline, plan))
+		}
+	}
+	return comparisons
+}
+
+// runIndex looks up the results of a run by revision, configuration and matrix cell, and by command when it is known
+type runIndex struct {
+	// byCommand holds the results with a command line, keyed by command, revision and scenario
+	byCommand map[string]PlanDetails
+
+	// withoutCommand holds the results without a command line, keyed by revision and scenario
+	withoutCommand map[string]PlanDetails
+
+	// all holds every result, keyed by revision and scenario
+	all map[string]PlanDetails
+}
+
+// newRunIndex returns an empty index
+func newRunIndex() *runIndex {
+	return &runIndex{
+		byCommand:      make(map[string]PlanDetails),
+		withoutCommand: make(map[string]PlanDetails),
+		all:            make(map[string]PlanDetails),
+	}
+}
+
+// add indexes a result at revision, its reference or commit
+func (index *runIndex) add(plan PlanDetails, revision string) {
+	index.all[runKey(plan, revision)] = plan
+	if len(plan.Command) > 0 {
+		index.byCommand[commandName(plan.Command)+"\x00"+runKey(plan, revision)] = plan
+	} else {
+		index.withoutCommand[runKey(plan, revision)] = plan
+	}
+}
+
+// find returns the result matching plan at revision: the result of the same command when both command lines are
+// known, otherwise one whose command is unknown on either side
+func (index *runIndex) find(plan PlanDetails, revision string) (PlanDetails, bool) {
+	if len(plan.Command) == 0 {
+		baseline, ok := index.all[runKey(plan, revision)]
+		return baseline, ok
+	}
+	if baseline, ok := index.byCommand[commandName(plan.Command)+"\x00"+runKey(plan, revision)]; ok {
+		return baseline, true
+	}
+	baseline, ok := index.withoutCommand[runKey(plan, revision)]
+	return baseline, ok
+}
+
+// runKey identifies a result of a run by revision, configuration and matrix cell
+func runKey(plan PlanDetails, revision string) string {
+	return revision + "\x00" + resultKey(plan)
+}
+
+// WriteComparison compares the results of new with old, see CompareRuns, and writes a table of the changes in the
+// median duration of the measured command in format, one of ComparisonText, ComparisonMarkdown or ComparisonJSON
+func WriteComparison(w io.Writer, old, new *Results, format string) error {
+	comparisons := CompareRuns(old, new)
+	switch format {
+	case ComparisonText, "":
+		return writeComparisonText(w, comparisons)
+	case ComparisonMarkdown:
+		return writeComparisonMarkdown(w, old, new, comparisons)
+	case ComparisonJSON:
+		return writeComparisonJSON(w, comparisons)
+	default:
+		return fmt.Errorf("unknown comparison format %q: use %s, %s or %s", format, ComparisonText, ComparisonMarkdown, ComparisonJSON)
+	}
+}
+
+// writeComparisonText writes a row per comparison aligned in columns
+func writeComparisonText(w io.Writer, comparisons []Comparison) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tRESULT\tOLD SHA\tNEW SHA\tOLD (s)\tNEW (s)\tDELTA\tP\tSTATUS")
+
+	for _, c := range comparisons {
+		p, status := "n/a", "~"
+		if !math.IsNaN(c.P) {
+			p = fmt.Sprintf("%.3f", c.P)
+		}
+		switch {
+		case c.Regression():
+			status = "regression"
+		case c.Improvement():
+			status = "improvement"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.2f ± %.2f\t%.2f ± %.2f\t%+.1f%%\t%s\t%s\n",
+			commandName(c.Result.Command), resultName(c.Result), orDash(shortSHA(c.Baseline.SHA)), orDash(shortSHA(c.Result.SHA)),
+			c.Old.Median, c.Old.StdDev, c.New.Median, c.New.StdDev, c.Delta, p, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "\nMedians ± standard deviation. p-values are from a two-sided Mann-Whitney U test, significant below %g.\n", significanceLevel)
+	return err
+}
+
+// writeComparisonMarkdown writes the comparisons as the table of the Markdown report
+func writeComparisonMarkdown(w io.Writer, old, new *Results, comparisons []Comparison) error {
+	var regressions, improvements int
+	for _, c := range comparisons {
+		if c.Regression() {
+			regressions++
+		}
+		if c.Improvement() {
+			improvements++
+		}
+	}
+
+	var report strings.Builder
+	title := "Terraform provider benchmark"
+	if len(comparisons) > 0 {
+		title += ": " + commandName(comparisons[0].Result.Command)
+	}
+	fmt.Fprintf(&report, "## %s\n\n", title)
+	fmt.Fprintf(&report, "Compared %s with %s: %d %s, %d %s.\n\n", markdownRun(new), markdownRun(old),
+		regressions, plural(regressions, "regression"), improvements, plural(improvements, "improvement"))
+	report.WriteString("| | Result | Median (s) | Δ | p |\n|---|---|---:|---:|---:|\n")
+
+	for _, c := range comparisons {
+		name := markdownEscape(resultName(c.Result))
+		if c.Baseline.SHA != c.Result.SHA {
+			name += fmt.Sprintf(" (`%s` → `%s`)", orDash(shortSHA(c.Baseline.SHA)), orDash(shortSHA(c.Result.SHA)))
+		}
+		emoji, delta, p := markdownComparison(c)
+		fmt.Fprintf(&report, "| %s | %s | %.2f ± %.2f | %s | %s |\n", emoji, name, c.New.Median, c.New.StdDev, delta, p)
+	}
+
+	fmt.Fprintf(&report, "\n_Medians ± standard deviation of the measured command. p-values are from a two-sided Mann-Whitney U test and `*` marks p < %g._\n", significanceLevel)
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// markdownRun describes a run by its ID and start time, when known
+func markdownRun(results *Results) string {
+	var parts []string
+	if results.RunID != "" {
+		parts = append(parts, "`"+results.RunID+"`")
+	}
+	if !results.Start.IsZero() {
+		parts = append(parts, results.Start.UTC().Format("2006-01-02 15:04 UTC"))
+	}
+	if len(parts) == 0 {
+		return "run"
+	}
+	return "run " + strings.Join(parts, " from ")
+}
+
+// comparisonRow is a comparison as written by WriteComparison in JSON
+type comparisonRow struct {
+	Command  string     `json:"command"`
+	Ref      string     `json:"ref"`
+	Scenario string     `json:"scenario,omitempty"`
+	OldRef   string     `json:"old_ref"`
+	OldSHA   string     `json:"old_sha,omitempty"`
+	NewSHA   string     `json:"new_sha,omitempty"`
+	Old      Statistics `json:"old"`
+	New      Statistics `json:"new"`
+	Delta    float64    `json:"delta"`
+
+	// P is omitted when it could not be calculated
+	P           *float64 `json:"p,omitempty"`
+	Significant bool     `json:"significant"`
+}
+
+// writeComparisonJSON writes the comparisons as a JSON array
+func writeComparisonJSON(w io.Writer, comparisons []Comparison) error {
+	rows := make([]comparisonRow, len(comparisons))
+	for i, c := range comparisons {
+		rows[i] = comparisonRow{
+			Command:     commandName(c.Result.Command),
+			Ref:         c.Result.Version,
+			Scenario:    scenarioName(c.Result),
+			OldRef:      c.Baseline.Version,
+			OldSHA:      c.Baseline.SHA,
+			NewSHA:      c.Result.SHA,
+			Old:         c.Old,
+			New:         c.New,
+			Delta:       c.Delta,
+			Significant: c.Significant(),
+		}
+		if !math.IsNaN(c.P) {
+			rows[i].P = &c.P
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	return encoder.Encode(rows)
+}
+
+// orDash returns s, or "-" if s is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareRuns(t *testing.T) {
+	plan := []string{"terraform", "plan"}
+	old := &Results{Results: []PlanDetails{
+		{Version: "main", SHA: "aaaa", Command: plan, Config: "a", Samples: samplesOf(10, 10.1, 9.9, 10, 10.2)},
+		{Version: "main", SHA: "aaaa", Command: plan, Config: "b", Samples: samplesOf(5, 5, 5, 5, 5)},
+		{Version: "v1.2.0", SHA: "cccc", Command: plan, Config: "a", Samples: samplesOf(8)},
+		{Version: "feature", SHA: "dddd", Command: plan, Config: "a", Failure: &Failure{Phase: phaseCommand, Iteration: 1}},
+	}}
+	new := &Results{Results: []PlanDetails{
+		{Version: "main", SHA: "bbbb", Command: plan, Config: "a", Samples: samplesOf(12, 12.1, 11.9, 12, 12.2)},
+		{Version: "main", SHA: "bbbb", Command: []string{"terraform", "apply"}, Config: "b", Samples: samplesOf(5)},
+		{Version: "release", SHA: "cccc", Command: plan, Config: "a", Samples: samplesOf(9)},
+		{Version: "feature", SHA: "dddd", Command: plan, Config: "a", Samples: samplesOf(1)},
+		// Failed results are not compared, as their timings are incomplete
+		{Version: "v1.2.0", SHA: "cccc", Command: plan, Config: "a", Failure: &Failure{Phase: phaseCommand, Iteration: 1}},
+	}}
+
+	comparisons := CompareRuns(old, new)
+	if len(comparisons) != 2 {
+		t.Fatalf("CompareRuns() returned %d comparisons, want 2", len(comparisons))
+	}
+
+	// The same reference is matched, even at a different commit
+	if c := comparisons[0]; c.Baseline.SHA != "aaaa" || c.Result.SHA != "bbbb" || !c.Regression() {
+		t.Errorf("first comparison = %s %s → %s %s (regression %t), want a regression from main aaaa to main bbbb",
+			c.Baseline.Version, c.Baseline.SHA, c.Result.Version, c.Result.SHA, c.Regression())
+	}
+	// Otherwise the same commit under another name
+	if c := comparisons[1]; c.Baseline.Version != "v1.2.0" || c.Result.Version != "release" {
+		t.Errorf("second comparison = %s → %s, want v1.2.0 → release", c.Baseline.Version, c.Result.Version)
+	}
+}
+
+func TestCompareRuns_legacy(t *testing.T) {
+	// Legacy documents only recorded the reference and mean duration of each result
+	legacy, err := ReadResults(strings.NewReader(`[{"version": "main", "duration": 10}, {"version": "v1.2.0", "duration": 8}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	current := &Results{Results: []PlanDetails{
+		{Version: "main", SHA: "bbbb", Command: []string{"terraform", "plan"}, Samples: samplesOf(12)},
+		{Version: "feature", SHA: "dddd", Command: []string{"terraform", "plan"}, Samples: samplesOf(9)},
+	}}
+
+	comparisons := CompareRuns(legacy, current)
+	if len(comparisons) != 1 || comparisons[0].Baseline.Version != "main" || comparisons[0].Result.SHA != "bbbb" {
+		t.Fatalf("CompareRuns() = %+v, want main compared with the legacy result", comparisons)
+	}
+	if comparisons[0].Old.Median != 10 || comparisons[0].New.Median != 12 {
+		t.Errorf("compared medians %v → %v, want 10 → 12", comparisons[0].Old.Median, comparisons[0].New.Median)
+	}
+
+	// Either side can be the legacy one
+	if comparisons := CompareRuns(current, legacy); len(comparisons) != 1 || comparisons[0].Result.Version != "main" {
+		t.Errorf("CompareRuns() = %+v, want the legacy result compared with main", comparisons)
+	}
+}
+
+func TestWriteComparison(t *testing.T) {
+	old := &Results{RunID: "0a1b2c3d", Start: time.Date(2024, 5, 1, 2, 0, 0, 0, time.UTC), Results: testResults()}
+	new := &Results{RunID: "4e5f6a7b", Results: testResults()}
+	new.Results[0].SHA = "def5678"
+	new.Results[0].Samples = samplesOf(3, 3.1)
+
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{format: ComparisonText, expected: []string{
+			"COMMAND  RESULT",
+			"main small-org [log=DEBUG,parallelism=10]  abc1234  def5678  2.25 ± 0.35  3.05 ± 0.07  +35.6%  0.333  ~",
+		}},
+		{format: ComparisonMarkdown, expected: []string{
+			"Compared run `4e5f6a7b` with run `0a1b2c3d` from 2024-05-01 02:00 UTC: 0 regressions, 0 improvements.",
+			"| ⚪ | main small-org [log=DEBUG,parallelism=10] (`abc1234` → `def5678`) | 3.05 ± 0.07 | +35.6% | 0.333 |",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteComparison(&buf, old, new, tt.format); err != nil {
+				t.Fatalf("WriteComparison() error = %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("expected %q in:\n%s", expected, buf.String())
+				}
+			}
+		})
+	}
+
+	t.Run(ComparisonJSON, func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteComparison(&buf, old, new, ComparisonJSON); err != nil {
+			t.Fatalf("WriteComparison() error = %v", err)
+		}
+		var rows []map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &rows); err != nil {
+			t.Fatalf("failed to parse output: %v", err)
+		}
+		if len(rows) != 1 || rows[0]["old_sha"] != "abc1234" || rows[0]["new_sha"] != "def5678" || rows[0]["significant"] != false {
+			t.Errorf("unexpected rows %v", rows)
+		}
+	})
+
+	if err := WriteComparison(&bytes.Buffer{}, old, new, "yaml"); err == nil {
+		t.Error("WriteComparison() expected error for an unknown format")
+	}
+}
@@ -0,0 +1,207 @@
+package benchmark
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// significanceLevel is the p-value below which a difference between two references is reported as significant
+const significanceLevel = 0.05
+
+// Comparison compares the measured command durations of a result with the baseline reference's result for the same
+// configuration and matrix cell
+type Comparison struct {
+	Baseline PlanDetails
+	Result   PlanDetails
+
+	// Old and New describe the durations of the baseline and the result
+	Old Statistics
+	New Statistics
+
+	// Delta is the percentage change of the median duration from the baseline
+	Delta float64
+
+	// P is the two-sided p-value of the Mann-Whitney U test, or NaN when either side has no durations
+	P float64
+}
+
+// Significant is true when the difference is unlikely to be noise
+func (c Comparison) Significant() bool {
+	return !math.IsNaN(c.P) && c.P < significanceLevel
+}
+
+// Regression is true when the result is significantly slower than the baseline
+func (c Comparison) Regression() bool {
+	return c.Significant() && c.Delta > 0
+}
+
+// Improvement is true when the result is significantly faster than the baseline
+func (c Comparison) Improvement() bool {
+	return c.Significant() && c.Delta < 0
+}
+
+// compareResults compares every result of a reference other than baseline with the baseline's result for the same
+// configuration and matrix cell. Results without a matching baseline are skipped.
+func compareResults(data []PlanDetails, baseline string) []Comparison {
+	baselines := make(map[string]PlanDetails)
+	for _, plan := range data {
+		if plan.Version == baseline && plan.Failure == nil {
+			baselines[resultKey(plan)] = plan
+		}
+	}
+
+	var comparisons []Comparison
+	for _, plan := range data {
+		base, ok := baselines[resultKey(plan)]
+		if !ok || plan.Version == baseline || plan.Failure != nil {
+			continue
+		}
+		comparisons = append(comparisons, newComparison(base, plan))
+	}
+	return comparisons
+}
+
+// comparisonKey identifies a result by reference, configuration and matrix cell
+func comparisonKey(plan PlanDetails) string {
+	return plan.Version + "\x00" + resultKey(plan)
+}
+
+// checkRegressions records every result that is significantly slower than the baseline reference's by more than
+// MaxRegressionPercent, returning an error listing them
+func (b *Benchmark) checkRegressions(data []PlanDetails) error {
+	regressions := make(map[string]Comparison)
+	for _, c := range compareResults(data, b.baselineReference()) {
+		if c.Regression() && c.Delta > b.MaxRegressionPercent {
+			regressions[comparisonKey(c.Result)] = c
+		}
+	}
+
+	var messages []string
+	for i, plan := range data {
+		c, ok := regressions[comparisonKey(plan)]
+		if !ok {
+			continue
+		}
+		data[i].Regression = &RegressionDetails{Baseline: b.baselineReference(), Delta: c.Delta, P: c.P}
+		message := fmt.Sprintf("%s is %.1f%% slower than %s (p=%.3f)", resultName(plan), c.Delta, b.baselineReference(), c.P)
+		b.logMessage(LogLevelInfo, "🐢 %s", message)
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("regressions exceed %g%%: %s", b.MaxRegressionPercent, strings.Join(messages, "; "))
+}
+
+// newComparison compares result with baseline
+func newComparison(baseline, result PlanDetails) Comparison {
+	oldDurations, newDurations := commandDurations(baseline), commandDurations(result)
+	c := Comparison{
+		Baseline: baseline,
+		Result:   result,
+		Old:      summarise(oldDurations),
+		New:      summarise(newDurations),
+		P:        mannWhitneyU(oldDurations, newDurations),
+	}
+	if c.Old.Median > 0 {
+		c.Delta = (c.New.Median - c.Old.Median) / c.Old.Median * 100
+	}
+	return c
+}
+
+// mannWhitneyU returns the two-sided p-value of the Mann-Whitney U test of whether x and y come from the same
+// distribution. Small samples without ties use the exact distribution of U, otherwise the normal approximation with a
+// tie correction is used. NaN is returned when either sample is empty.
+func mannWhitneyU(x, y []float64) float64 {
+	n1, n2 := len(x), len(y)
+	if n1 == 0 || n2 == 0 {
+		return math.NaN()
+	}
+
+	type value struct {
+		v     float64
+		first bool
+	}
+	values := make([]value, 0, n1+n2)
+	for _, v := range x {
+		values = append(values, value{v: v, first: true})
+	}
+	for _, v := range y {
+		values = append(values, value{v: v})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+
+	// Rank the values, giving tied values the mean of their ranks
+	var rankSum, tieCorrection float64
+	ties := false
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].first {
+				rankSum += rank
+			}
+		}
+		if t := float64(j - i); t > 1 {
+			ties = true
+			tieCorrection += t*t*t - t
+		}
+		i = j
+	}
+	u := rankSum - float64(n1*(n1+1))/2
+
+	if !ties && n1+n2 <= 40 {
+		return exactMannWhitneyP(n1, n2, u)
+	}
+
+	n := float64(n1 + n2)
+	mean := float64(n1*n2) / 2
+	sigma := math.Sqrt(float64(n1*n2) / 12 * ((n + 1) - tieCorrection/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := max(math.Abs(u-mean)-0.5, 0) / sigma
+	return math.Min(1, math.Erfc(z/math.Sqrt2))
+}
+
+// exactMannWhitneyP returns the two-sided p-value of U using its exact distribution for samples of size n1 and n2
+func exactMannWhitneyP(n1, n2 int, u float64) float64 {
+	// counts[i][j][k] is the number of orderings of i and j values whose U statistic is k, built up one sample at a time
+	maxU := n1 * n2
+	counts := make([][][]float64, n1+1)
+	for i := range counts {
+		counts[i] = make([][]float64, n2+1)
+		for j := range counts[i] {
+			counts[i][j] = make([]float64, maxU+1)
+			if i == 0 || j == 0 {
+				counts[i][j][0] = 1
+				continue
+			}
+			for k := 0; k <= i*j; k++ {
+				// The largest value is from the first sample, beating all j values of the second, or from the second
+				if k >= j {
+					counts[i][j][k] += counts[i-1][j][k-j]
+				}
+				counts[i][j][k] += counts[i][j-1][k]
+			}
+		}
+	}
+
+	var total, lower, upper float64
+	for k, count := range counts[n1][n2] {
+		total += count
+		if float64(k) <= u {
+			lower += count
+		}
+		if float64(k) >= u {
+			upper += count
+		}
+	}
+	return math.Min(1, 2*math.Min(lower, upper)/total)
+}
@@ -0,0 +1,91 @@
+package benchmark
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	tests := []struct {
+		name     string
+		x, y     []float64
+		expected float64
+	}{
+		{name: "separated samples of three", x: []float64{1, 2, 3}, y: []float64{4, 5, 6}, expected: 0.1},
+		{name: "separated samples of five", x: []float64{1, 2, 3, 4, 5}, y: []float64{6, 7, 8, 9, 10}, expected: 2.0 / 252},
+		{name: "interleaved samples", x: []float64{1, 3, 5}, y: []float64{2, 4, 6}, expected: 0.7},
+		{name: "single values", x: []float64{1}, y: []float64{2}, expected: 1},
+		{name: "identical values", x: []float64{2, 2, 2}, y: []float64{2, 2, 2}, expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p := mannWhitneyU(tt.x, tt.y); math.Abs(p-tt.expected) > 1e-9 {
+				t.Errorf("mannWhitneyU() = %v, want %v", p, tt.expected)
+			}
+		})
+	}
+
+	if p := mannWhitneyU(nil, []float64{1}); !math.IsNaN(p) {
+		t.Errorf("mannWhitneyU() of an empty sample = %v, want NaN", p)
+	}
+
+	// Ties fall back to the normal approximation, which should still find clearly separated samples significant
+	if p := mannWhitneyU([]float64{1, 1, 2, 2, 3, 3}, []float64{7, 7, 8, 8, 9, 9}); p >= significanceLevel {
+		t.Errorf("mannWhitneyU() with ties = %v, want < %v", p, significanceLevel)
+	}
+}
+
+func samplesOf(durations ...float64) []Sample {
+	samples := make([]Sample, len(durations))
+	for i, d := range durations {
+		samples[i] = Sample{Iteration: i + 1, Phases: []Phase{{Name: phaseCommand, Duration: d}}}
+	}
+	return samples
+}
+
+func TestCompareResults(t *testing.T) {
+	data := []PlanDetails{
+		{Version: "main", Config: "a", Samples: samplesOf(10, 10.1, 9.9, 10, 10.2)},
+		{Version: "main", Config: "b", Samples: samplesOf(5, 5, 5, 5, 5)},
+		{Version: "feature", Config: "a", Samples: samplesOf(12, 12.1, 11.9, 12, 12.2)},
+		{Version: "feature", Config: "c", Samples: samplesOf(1)},
+	}
+
+	comparisons := compareResults(data, "main")
+	if len(comparisons) != 1 {
+		t.Fatalf("compareResults() returned %d comparisons, want 1", len(comparisons))
+	}
+
+	c := comparisons[0]
+	if c.Baseline.Config != "a" || c.Result.Version != "feature" {
+		t.Errorf("compared %s/%s with %s/%s", c.Result.Version, c.Result.Config, c.Baseline.Version, c.Baseline.Config)
+	}
+	if math.Abs(c.Delta-20) > 1e-9 {
+		t.Errorf("Delta = %v, want 20", c.Delta)
+	}
+	if !c.Regression() || c.Improvement() {
+		t.Errorf("expected a significant regression, got p = %v", c.P)
+	}
+}
+
+func TestBenchmark_checkRegressions(t *testing.T) {
+	data := []PlanDetails{
+		{Version: "main", Samples: samplesOf(10, 10.1, 9.9, 10, 10.2)},
+		{Version: "slightly-slower", Samples: samplesOf(10.5, 10.6, 10.4, 10.5, 10.7)},
+		{Version: "much-slower", Samples: samplesOf(12, 12.1, 11.9, 12, 12.2)},
+	}
+	b := &Benchmark{References: []string{"main", "slightly-slower", "much-slower"}, MaxRegressionPercent: 10}
+
+	err := b.checkRegressions(data)
+	if err == nil || !strings.Contains(err.Error(), "much-slower is 20.0% slower than main") {
+		t.Errorf("checkRegressions() = %v", err)
+	}
+	if data[1].Regression != nil {
+		t.Errorf("expected regression within the limit to pass, got %+v", data[1].Regression)
+	}
+	if data[2].Regression == nil || data[2].Regression.Baseline != "main" || math.Abs(data[2].Regression.Delta-20) > 1e-9 {
+		t.Errorf("unexpected regression %+v", data[2].Regression)
+	}
+}
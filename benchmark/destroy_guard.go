@@ -0,0 +1,160 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// plannedChange is a resource change reported by terraform's machine readable plan output
+type plannedChange struct {
+	Address      string
+	ResourceType string
+	Action       string
+}
+
+// planMessage is a line of terraform's machine readable (-json) UI output. Only planned_change messages are decoded.
+type planMessage struct {
+	Type   string `json:"type"`
+	Change struct {
+		Action   string `json:"action"`
+		Resource struct {
+			Addr         string `json:"addr"`
+			ResourceType string `json:"resource_type"`
+		} `json:"resource"`
+	} `json:"change"`
+}
+
+// previewLogFilePath returns the log of the preview of a destroy logged to destroyPath, e.g. destroy_preview.log
+func previewLogFilePath(destroyPath string) string {
+	return strings.TrimSuffix(destroyPath, ".log") + "_preview.log"
+}
+
+// commandPreviewLogFilePath returns the log of the preview of the measured destroy in an iteration of a Destroy
+// benchmark, e.g. main_preview_2.log
+func (ws *workspace) commandPreviewLogFilePath(r run) string {
+	return ws.iterationLogFilePath(r, "preview")
+}
+
+// parsePlannedChanges reads the planned changes from terraform's machine readable UI output, skipping any lines that
+// are not JSON
+func parsePlannedChanges(r io.Reader) ([]plannedChange, error) {
+	var changes []plannedChange
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message planMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil || message.Type != "planned_change" {
+			continue
+		}
+		changes = append(changes, plannedChange{
+			Address:      message.Change.Resource.Addr,
+			ResourceType: message.Change.Resource.ResourceType,
+			Action:       message.Change.Action,
+		})
+	}
+	return changes, scanner.Err()
+}
+
+// previewDestroy runs terraform plan -destroy in the workspace, writing its output to outputPath, and returns the
+// resources the destroy would remove
+func (b *Benchmark) previewDestroy(ws *workspace, outputPath string) ([]plannedChange, error) {
+	command := append(b.buildCommand(ws, Plan, false), "-destroy", "-json", "-input=false")
+	b.logMessage(LogLevelDebug, "Running %v in directory %s", redactCommand(command), ws.dir)
+
+	if err := b.runSetupCommand(ws, command, outputPath); err != nil {
+		return nil, fmt.Errorf("destroy preview failed: %v", err)
+	}
+
+	outputFile, err := os.Open(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer outputFile.Close()
+
+	changes, err := parsePlannedChanges(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read destroy preview: %w", err)
+	}
+
+	var deletions []plannedChange
+	for _, change := range changes {
+		if change.Action == "delete" {
+			deletions = append(deletions, change)
+		}
+	}
+	return deletions, nil
+}
+
+// checkDestroyGuard refuses a destroy that would remove more than MaxDestroyResources resources, or any resource whose
+// type and address are both missing from DestroyAllowlist
+func (b *Benchmark) checkDestroyGuard(deletions []plannedChange) error {
+	if b.MaxDestroyResources > 0 && len(deletions) > b.MaxDestroyResources {
+		return fmt.Errorf("destroy would remove %d resources, which exceeds the maximum of %d", len(deletions), b.MaxDestroyResources)
+	}
+
+	if len(b.DestroyAllowlist) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(b.DestroyAllowlist))
+	for _, entry := range b.DestroyAllowlist {
+		allowed[entry] = true
+	}
+
+	var refused []string
+	for _, deletion := range deletions {
+		if !allowed[deletion.ResourceType] && !allowed[deletion.Address] {
+			refused = append(refused, deletion.Address)
+		}
+	}
+	if len(refused) > 0 {
+		return fmt.Errorf("destroy would remove resources outside the allowlist: %s", strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+// summariseDeletions returns the number of resources of each type being removed, e.g. "genesyscloud_user x3"
+func summariseDeletions(deletions []plannedChange) string {
+	counts := make(map[string]int)
+	for _, deletion := range deletions {
+		counts[deletion.ResourceType]++
+	}
+
+	types := make([]string, 0, len(counts))
+	for resourceType := range counts {
+		types = append(types, resourceType)
+	}
+	sort.Strings(types)
+
+	parts := make([]string, len(types))
+	for i, resourceType := range types {
+		parts[i] = fmt.Sprintf("%s x%d", resourceType, counts[resourceType])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// guardDestroy previews the destroy, writing the preview's output to previewPath, logs what it would remove and checks
+// it against the configured limits
+func (b *Benchmark) guardDestroy(ws *workspace, previewPath string) error {
+	deletions, err := b.previewDestroy(ws, previewPath)
+	if err != nil {
+		return err
+	}
+
+	if len(deletions) == 0 {
+		b.logMessage(LogLevelInfo, "🔎 Destroy will not remove any resources")
+	} else {
+		b.logMessage(LogLevelInfo, "🔎 Destroy will remove %d resources: %s", len(deletions), summariseDeletions(deletions))
+	}
+
+	if err := b.checkDestroyGuard(deletions); err != nil {
+		return fmt.Errorf("refusing to destroy: %w", err)
+	}
+	return nil
+}
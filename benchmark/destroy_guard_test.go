@@ -0,0 +1,136 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const testDestroyPlanOutput = `{"@level":"info","@message":"Terraform 1.9.5","type":"version","terraform":"1.9.5","ui":"1.2"}
+{"@level":"info","@message":"genesyscloud_user.alice: Plan to delete","type":"planned_change","change":{"resource":{"addr":"genesyscloud_user.alice","resource_type":"genesyscloud_user","resource_name":"alice"},"action":"delete"}}
+{"@level":"info","@message":"genesyscloud_user.bob: Plan to delete","type":"planned_change","change":{"resource":{"addr":"genesyscloud_user.bob","resource_type":"genesyscloud_user","resource_name":"bob"},"action":"delete"}}
+{"@level":"info","@message":"module.queues.genesyscloud_routing_queue.q: Plan to delete","type":"planned_change","change":{"resource":{"addr":"module.queues.genesyscloud_routing_queue.q","resource_type":"genesyscloud_routing_queue","resource_name":"q"},"action":"delete"}}
+not json
+{"@level":"info","@message":"Plan: 0 to add, 0 to change, 3 to destroy.","type":"change_summary","changes":{"add":0,"change":0,"remove":3,"operation":"destroy"}}
+`
+
+func TestParsePlannedChanges(t *testing.T) {
+	changes, err := parsePlannedChanges(strings.NewReader(testDestroyPlanOutput))
+	if err != nil {
+		t.Fatalf("parsePlannedChanges() error = %v", err)
+	}
+
+	expected := []plannedChange{
+		{Address: "genesyscloud_user.alice", ResourceType: "genesyscloud_user", Action: "delete"},
+		{Address: "genesyscloud_user.bob", ResourceType: "genesyscloud_user", Action: "delete"},
+		{Address: "module.queues.genesyscloud_routing_queue.q", ResourceType: "genesyscloud_routing_queue", Action: "delete"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("parsePlannedChanges() returned %d changes, want %d", len(changes), len(expected))
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("change %d = %+v, want %+v", i, changes[i], expected[i])
+		}
+	}
+}
+
+func TestBenchmark_checkDestroyGuard(t *testing.T) {
+	deletions, err := parsePlannedChanges(strings.NewReader(testDestroyPlanOutput))
+	if err != nil {
+		t.Fatalf("parsePlannedChanges() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		benchmark *Benchmark
+		errMsg    string
+	}{
+		{name: "no limits", benchmark: &Benchmark{}},
+		{name: "within maximum", benchmark: &Benchmark{MaxDestroyResources: 3}},
+		{name: "exceeds maximum", benchmark: &Benchmark{MaxDestroyResources: 2}, errMsg: "exceeds the maximum of 2"},
+		{
+			name:      "allowed by type and address",
+			benchmark: &Benchmark{DestroyAllowlist: []string{"genesyscloud_user", "module.queues.genesyscloud_routing_queue.q"}},
+		},
+		{
+			name:      "outside allowlist",
+			benchmark: &Benchmark{DestroyAllowlist: []string{"genesyscloud_user"}},
+			errMsg:    "outside the allowlist: module.queues.genesyscloud_routing_queue.q",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.benchmark.checkDestroyGuard(deletions)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("checkDestroyGuard() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("checkDestroyGuard() error = %v, want %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestSummariseDeletions(t *testing.T) {
+	deletions, err := parsePlannedChanges(strings.NewReader(testDestroyPlanOutput))
+	if err != nil {
+		t.Fatalf("parsePlannedChanges() error = %v", err)
+	}
+
+	expected := "genesyscloud_routing_queue x1, genesyscloud_user x2"
+	if summary := summariseDeletions(deletions); summary != expected {
+		t.Errorf("summariseDeletions() = %v, want %v", summary, expected)
+	}
+}
+
+// installFakeCommand puts a shell script named name running script on PATH for the rest of the test
+func installFakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake commands are shell scripts")
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestBenchmark_measureIteration_guardsMeasuredDestroy(t *testing.T) {
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(planPath, []byte(testDestroyPlanOutput), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The preview removes 3 resources, and the measured destroy leaves a marker behind
+	installFakeCommand(t, "terraform", `case "$*" in
+*-destroy*) cat "`+planPath+`" ;;
+destroy*) touch destroyed ;;
+esac
+`)
+
+	b := &Benchmark{TfCommand: Destroy, MaxDestroyResources: 2}
+	ws := &workspace{dir: t.TempDir(), logsDir: t.TempDir()}
+	r := run{reference: "main", iteration: 1}
+
+	_, _, err := b.measureIteration(ws, r, &PlanDetails{})
+	if err == nil || !strings.Contains(err.Error(), "destroy would remove 3 resources, which exceeds the maximum of 2") {
+		t.Fatalf("measureIteration() error = %v, want the destroy to be refused", err)
+	}
+	if phase := failurePhase(err); phase != phasePrepare {
+		t.Errorf("failurePhase() = %v, want %v", phase, phasePrepare)
+	}
+	if _, err := os.Stat(filepath.Join(ws.dir, "destroyed")); err == nil {
+		t.Error("the measured destroy ran after being refused")
+	}
+	if path, expected := b.phaseLogFilePath(ws, r, phasePrepare), filepath.Join(ws.logsDir, "main_preview_1.log"); path != expected {
+		t.Errorf("phaseLogFilePath() = %v, want %v", path, expected)
+	}
+}
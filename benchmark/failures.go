@@ -0,0 +1,135 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// failureLogLines is the number of lines at the end of a failing step's log that are recorded with the failure
+const failureLogLines = 20
+
+// newFailure records err, attributing it to the phase it occurred in and capturing the end of that phase's log
+func (b *Benchmark) newFailure(ws *workspace, r run, err error) *Failure {
+	failure := &Failure{Phase: failurePhase(err), Iteration: r.iteration, Error: err.Error()}
+	if failure.Phase == phaseCheckout || failure.Phase == phaseBuild || failure.Phase == phaseInit {
+		failure.Iteration = 0
+	}
+
+	if logPath := b.phaseLogFilePath(ws, r, failure.Phase); logPath != "" {
+		failure.Log = b.relativeOutputPath(logPath)
+		if tail, err := tailFile(logPath, failureLogLines); err == nil {
+			failure.LogTail = tail
+		}
+	}
+	return failure
+}
+
+// failurePhase returns the phase err occurred in. Errors outside a phase happened while checking out the reference.
+func failurePhase(err error) string {
+	var phaseErr *phaseError
+	if errors.As(err, &phaseErr) {
+		return phaseErr.phase
+	}
+	return phaseCheckout
+}
+
+// withLogTail returns err followed by the last lines of the log written by the phase it occurred in, so that the cause
+// of a failure is visible without opening the log
+func (b *Benchmark) withLogTail(ws *workspace, r run, err error) error {
+	return appendLogTail(err, b.phaseLogFilePath(ws, r, failurePhase(err)))
+}
+
+// appendLogTail returns err followed by the last lines of the log at path, or err itself if the log is missing or empty
+func appendLogTail(err error, path string) error {
+	if err == nil || path == "" {
+		return err
+	}
+	tail, tailErr := tailFile(path, failureLogLines)
+	if tailErr != nil || tail == "" {
+		return err
+	}
+	return fmt.Errorf("%w\nLast lines of %s:\n%s", err, path, tail)
+}
+
+// phaseLogFilePath returns the log file written by a phase of a run, or "" if the phase has no log
+func (b *Benchmark) phaseLogFilePath(ws *workspace, r run, phase string) string {
+	switch phase {
+	case phaseCheckout, phaseBuild:
+		return b.stepLogFilePath(referenceLogName(r.reference), phase)
+	case phaseInit:
+		return ws.logFilePath(logFileName(referenceLogName(r.reference) + "_init"))
+	case phasePrepare:
+		path := b.prepareLogFilePath(ws, r)
+		if b.TfCommand == Destroy {
+			// Once the apply succeeds the measured destroy is previewed, and refused if it breaks the destroy guard
+			if _, err := os.Stat(ws.commandPreviewLogFilePath(r)); err == nil {
+				return ws.commandPreviewLogFilePath(r)
+			}
+		}
+		if ws.snapshot == "" && b.TfCommand.spec().preRun == Destroy {
+			// A destroy refused by the destroy guard, or whose preview failed, only wrote the preview's log
+			_, destroyErr := os.Stat(path)
+			_, previewErr := os.Stat(previewLogFilePath(path))
+			if destroyErr != nil && previewErr == nil {
+				return previewLogFilePath(path)
+			}
+		}
+		return path
+	case phaseCommand:
+		return ws.commandLogFilePath(r)
+	case phaseShow:
+		return ws.logFilePath(logFileName(r.logName() + "_show"))
+	case phaseIdempotency:
+		return ws.idempotencyLogFilePath(r)
+	}
+	return ""
+}
+
+// failedResults returns a result recording err for every matrix cell of a run that could not be measured
+func (b *Benchmark) failedResults(ws *workspace, r run, setup []Phase, err error) []PlanDetails {
+	failure := b.newFailure(ws, r, err)
+
+	var data []PlanDetails
+	for _, cell := range b.matrixCells() {
+		r.cell = cell
+		data = append(data, PlanDetails{
+			Version: r.reference,
+			SHA:     r.sha,
+			Setup:   setup,
+			Command: redactCommand(b.measuredCommand(ws, r)),
+			Config:  ws.config.Name,
+			Cell:    r.cell.values(),
+			Failure: failure,
+		})
+	}
+	return data
+}
+
+// Failed is true when the result failed, was slower than MaxRegressionPercent allows, was not idempotent or planned
+// differently from the baseline
+func (plan PlanDetails) Failed() bool {
+	return plan.Failure != nil || plan.Regression != nil || plan.Idempotency != nil && !plan.Idempotency.Empty || len(plan.PlanDifferences) > 0
+}
+
+// failureMessage describes a failure, e.g. "command failed in iteration 2: terraform command failed: exit status 1"
+func failureMessage(f *Failure) string {
+	if f.Iteration > 0 {
+		return fmt.Sprintf("%s failed in iteration %d: %s", f.Phase, f.Iteration, f.Error)
+	}
+	return fmt.Sprintf("%s failed: %s", f.Phase, f.Error)
+}
+
+// failuresError returns an error counting the failed results, or nil if none failed
+func failuresError(data []PlanDetails) error {
+	failed := 0
+	for _, plan := range data {
+		if plan.Failure != nil {
+			failed++
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d results failed", failed, len(data))
+}
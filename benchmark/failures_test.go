@@ -0,0 +1,119 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTailFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "command.log")
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tail, err := tailFile(path, 3)
+	if err != nil {
+		t.Fatalf("tailFile() error = %v", err)
+	}
+	if tail != "line 28\nline 29\nline 30" {
+		t.Errorf("tailFile() = %q", tail)
+	}
+
+	if _, err := tailFile(filepath.Join(t.TempDir(), "missing.log"), 3); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestBenchmark_newFailure(t *testing.T) {
+	t.Chdir(t.TempDir())
+	b := &Benchmark{TfCommand: Apply, OutputDir: "output"}
+	b.configureOutputPaths()
+	if err := os.MkdirAll(b.logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(b.logsDir, "main_2.log"), []byte("Planning...\nError: boom\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &workspace{logsDir: b.logsDir}
+	r := run{reference: "main", iteration: 2}
+
+	_, err := b.runPhase(phaseCommand, func() error { return errors.New("terraform command failed: exit status 1") })
+	failure := b.newFailure(ws, r, err)
+
+	if failure.Phase != phaseCommand || failure.Iteration != 2 || failure.Error != "terraform command failed: exit status 1" {
+		t.Errorf("unexpected failure %+v", failure)
+	}
+	if failure.Log != "logs/main_2.log" || failure.LogTail != "Planning...\nError: boom" {
+		t.Errorf("unexpected log %q with tail %q", failure.Log, failure.LogTail)
+	}
+
+	_, err = b.runPhase(phasePrepare, func() error { return errors.New("destroy failed") })
+	if failure := b.newFailure(ws, r, err); failure.Log != "logs/main_destroy_2.log" || failure.LogTail != "" {
+		t.Errorf("expected prepare failure to point at the iteration's destroy log, got %+v", failure)
+	}
+
+	// A destroy refused by the destroy guard points at the preview instead
+	if err := os.WriteFile(filepath.Join(b.logsDir, "main_destroy_2_preview.log"), []byte("Plan: 0 to add, 0 to change, 12 to destroy.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if failure := b.newFailure(ws, r, err); failure.Log != "logs/main_destroy_2_preview.log" || failure.LogTail != "Plan: 0 to add, 0 to change, 12 to destroy." {
+		t.Errorf("expected refused destroy to point at the preview log, got %+v", failure)
+	}
+
+	_, err = b.runPhase(phaseBuild, func() error { return errors.New("make sideload failed") })
+	if failure := b.newFailure(ws, r, err); failure.Iteration != 0 || failure.Log != "logs/main_build.log" {
+		t.Errorf("expected build failure without iteration pointing at the build log, got %+v", failure)
+	}
+}
+
+func TestBenchmark_withLogTail(t *testing.T) {
+	t.Chdir(t.TempDir())
+	b := &Benchmark{TfCommand: Plan, OutputDir: "output"}
+	b.configureOutputPaths()
+	if err := os.MkdirAll(b.logsDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	checkoutLog := filepath.Join(b.logsDir, "feature_fast-plan-2c3e5ffa_checkout.log")
+	if err := os.WriteFile(checkoutLog, []byte("error: pathspec 'feature/fast-plan' did not match any file(s) known to git\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := b.runPhase(phaseCheckout, func() error { return errors.New("git checkout failed: exit status 1") })
+	err = b.withLogTail(nil, run{reference: "feature/fast-plan"}, err)
+	expected := "git checkout failed: exit status 1\nLast lines of " + checkoutLog + ":\nerror: pathspec 'feature/fast-plan' did not match any file(s) known to git"
+	if err.Error() != expected {
+		t.Errorf("withLogTail() = %q, want %q", err, expected)
+	}
+	if failurePhase(err) != phaseCheckout {
+		t.Errorf("withLogTail() lost the phase of %v", err)
+	}
+
+	// Without a log the error is unchanged
+	_, err = b.runPhase(phaseBuild, func() error { return errors.New("make sideload failed") })
+	if tailed := b.withLogTail(nil, run{reference: "main"}, err); tailed != err {
+		t.Errorf("withLogTail() without a log = %q, want %q", tailed, err)
+	}
+	if appendLogTail(nil, checkoutLog) != nil {
+		t.Error("appendLogTail() of nil should be nil")
+	}
+}
+
+func TestFailuresError(t *testing.T) {
+	if err := failuresError([]PlanDetails{{Version: "main"}}); err != nil {
+		t.Errorf("failuresError() = %v, want nil", err)
+	}
+
+	err := failuresError([]PlanDetails{{Version: "main"}, {Version: "feature", Failure: &Failure{Phase: phaseBuild}}})
+	if err == nil || err.Error() != "1 of 2 results failed" {
+		t.Errorf("failuresError() = %v", err)
+	}
+}
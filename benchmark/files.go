@@ -1,32 +1,55 @@
 package benchmark
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
-// writeDataToFile writes collected timing data to JSON file
-func (b *Benchmark) writeDataToFile(data []PlanDetails) error {
-	var dataFilePath = filepath.Join(b.performanceDir, "data.json")
-	b.logMessage(LogLevelInfo, "Writing data to %s", dataFilePath)
+// terraformStateFiles are the top level entries of a configuration directory holding terraform's working data and
+// local state, which are not copied into temporary workspaces
+var terraformStateFiles = map[string]bool{
+	".terraform":               true,
+	"terraform.tfstate":        true,
+	"terraform.tfstate.backup": true,
+	"terraform.tfstate.d":      true,
+}
 
-	jsonData, err := json.MarshalIndent(data, "", "    ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %v", err)
-	}
+// writeDataToFile writes collected timing data to JSON file and with every configured result writer
+func (b *Benchmark) writeDataToFile(results *Results) error {
+	writers := append([]ResultWriter{JSONWriter{}}, b.ResultWriters...)
+	for _, writer := range writers {
+		dataFilePath := writer.FileName()
+		if !filepath.IsAbs(dataFilePath) {
+			dataFilePath = filepath.Join(b.performanceDir, dataFilePath)
+		}
+		b.logMessage(LogLevelInfo, "Writing data to %s", dataFilePath)
 
-	return os.WriteFile(dataFilePath, jsonData, 0644)
+		if err := writeResults(writer, dataFilePath, results); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// createOutputDirectories creates output directories and placeholder files
+// createOutputDirectories creates the output directories. Log files are only created by the steps that write them, so
+// the manifest never lists logs of steps that did not run.
 func (b *Benchmark) createOutputDirectories() error {
 	b.logMessage(LogLevelInfo, "🏗️ Creating output directories")
 	directories := []string{
 		b.logsDir,
 		b.performanceDir,
 	}
+	for _, config := range b.tfConfigs() {
+		directories = append(directories, b.configLogsDir(config))
+		if b.CheckPlanEquivalence {
+			directories = append(directories, filepath.Join(b.plansDir, config.Name))
+		}
+	}
 
 	for _, directory := range directories {
 		if err := os.MkdirAll(directory, 0755); err != nil {
@@ -34,39 +57,112 @@ func (b *Benchmark) createOutputDirectories() error {
 		}
 	}
 
-	b.logMessage(LogLevelInfo, "Creating output files")
+	if err := b.linkLatestRun(); err != nil {
+		// Symlinks may not be permitted, e.g. on Windows without developer mode
+		b.logMessage(LogLevelInfo, "⚠️ %v", err)
+	}
 
-	// Create placeholder files for all expected log files
-	for _, ref := range b.References {
-		// Create or truncate the file
-		file, err := os.Create(b.generateLogFilePath(ref))
+	b.logMessage(LogLevelInfo, "🏗️ Output directories created")
+	return nil
+}
+
+// copyDir copies the contents of src into dst, overwriting existing files and skipping top level entries in exclude
+func copyDir(src, dst string, exclude map[string]bool) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("failed to create log file %s: %w", b.generateLogFilePath(ref), err)
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if exclude[rel] {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
-		file.Close()
+
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			os.Remove(target)
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target)
+		}
+	})
+}
+
+// copyFile copies the file at src to dst, creating or truncating dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
 	}
+	defer in.Close()
 
-	// Create destroy.log file
-	file, err := os.Create(b.destroyLogFilePath)
+	info, err := in.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to create destroy log file: %w", err)
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
 	}
-	file.Close()
+	return out.Close()
+}
+
+// copyFileIfExists copies the file at src to dst, doing nothing if src does not exist
+func copyFileIfExists(src, dst string) error {
+	if _, err := os.Stat(src); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return copyFile(src, dst)
+}
+
+// tailFile returns at most the last n lines of the file at path, reading no more than its last 64KiB
+func tailFile(path string, n int) (string, error) {
+	const maxBytes = 64 * 1024
 
-	// Create data.json file
-	file, err = os.Create(b.performanceFilePath)
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to create data file: %w", err)
+		return "", err
 	}
-	file.Close()
+	defer file.Close()
 
-	// Create init.log file
-	file, err = os.Create(b.initLogFilePath)
+	info, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to create init log file: %w", err)
+		return "", err
+	}
+	offset := max(info.Size()-maxBytes, 0)
+	buf := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(buf, offset); err != nil && err != io.EOF {
+		return "", err
 	}
-	file.Close()
 
-	b.logMessage(LogLevelInfo, "🏗️ Output directories and files created")
-	return nil
+	lines := strings.Split(strings.TrimRight(string(buf), "\n"), "\n")
+	if offset > 0 && len(lines) > 1 {
+		// The first line was probably cut short
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n"), nil
 }
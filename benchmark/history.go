@@ -0,0 +1,155 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const historyFileName = "history.jsonl"
+
+// History is an append-only store of results from every run of the benchmark, kept as JSON Lines with one result per
+// line so that runs never overwrite each other
+type History struct {
+	dir string
+}
+
+// HistoryEntry is a result recorded in the history along with the run that produced it
+type HistoryEntry struct {
+	RunID string `json:"run_id,omitempty"`
+
+	// Time is when the run started
+	Time time.Time `json:"time"`
+
+	Hostname string `json:"hostname,omitempty"`
+
+	Result PlanDetails `json:"result"`
+}
+
+// HistoryQuery selects entries from the history. Empty fields match every entry.
+type HistoryQuery struct {
+	// Ref matches the reference exactly
+	Ref string
+
+	// SHA matches commits starting with the given SHA
+	SHA string
+
+	// Scenario matches the configuration and matrix cell exactly, e.g. "small-org [parallelism=10]"
+	Scenario string
+
+	// Command matches the name of the measured command, e.g. "Plan"
+	Command string
+
+	// Since and Until limit the entries to runs started in [Since, Until)
+	Since time.Time
+	Until time.Time
+}
+
+// OpenHistory returns the history stored in dir. The directory is created when results are first appended.
+func OpenHistory(dir string) *History {
+	return &History{dir: dir}
+}
+
+// Scenario returns the configuration and matrix cell of the entry's result, or "" when neither was used
+func (e HistoryEntry) Scenario() string {
+	return scenarioName(e.Result)
+}
+
+// Append adds every result of a run to the history
+func (h *History) Append(results *Results) error {
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	var lines []byte
+	for _, plan := range results.Results {
+		line, err := json.Marshal(HistoryEntry{
+			RunID:    results.RunID,
+			Time:     results.Start,
+			Hostname: results.Environment.Hostname,
+			Result:   plan,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to marshal history entry: %w", err)
+		}
+		lines = append(append(lines, line...), '\n')
+	}
+
+	file, err := os.OpenFile(h.path(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	// A single write keeps the lines of a run together
+	if _, err := file.Write(lines); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to history: %w", err)
+	}
+	return file.Close()
+}
+
+// Query returns the entries matching q, oldest first. Lines that cannot be parsed, such as a line cut short by a
+// crash, are skipped.
+func (h *History) Query(q HistoryQuery) ([]HistoryEntry, error) {
+	file, err := os.Open(h.path())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		if q.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	return entries, nil
+}
+
+// matches is true when the entry satisfies every field of the query
+func (q HistoryQuery) matches(entry HistoryEntry) bool {
+	switch {
+	case q.Ref != "" && entry.Result.Version != q.Ref:
+		return false
+	case q.SHA != "" && !strings.HasPrefix(entry.Result.SHA, q.SHA):
+		return false
+	case q.Scenario != "" && entry.Scenario() != q.Scenario:
+		return false
+	case q.Command != "" && commandName(entry.Result.Command) != q.Command:
+		return false
+	case !q.Since.IsZero() && entry.Time.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !entry.Time.Before(q.Until):
+		return false
+	}
+	return true
+}
+
+// path returns the path of the history file
+func (h *History) path() string {
+	return filepath.Join(h.dir, historyFileName)
+}
+
+// appendHistory records the results of the run in the history
+func (b *Benchmark) appendHistory(results *Results) error {
+	b.logMessage(LogLevelInfo, "Appending results to history in %s", b.historyDir)
+	return OpenHistory(b.historyDir).Append(results)
+}
@@ -0,0 +1,83 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	history := OpenHistory(dir)
+
+	if entries, err := history.Query(HistoryQuery{}); err != nil || len(entries) != 0 {
+		t.Fatalf("Query() of an empty history = %v, %v", entries, err)
+	}
+
+	may := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	june := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	runs := []*Results{
+		{RunID: "june", Start: june, Results: []PlanDetails{
+			{Version: "main", SHA: "bbbb2222", Command: []string{"terraform", "plan"}, Config: "small"},
+		}},
+		{RunID: "may", Start: may, Environment: Environment{Hostname: "perf-runner-1"}, Results: []PlanDetails{
+			{Version: "main", SHA: "aaaa1111", Command: []string{"terraform", "plan"}, Config: "small"},
+			{Version: "main", SHA: "aaaa1111", Command: []string{"terraform", "plan"}, Config: "large"},
+		}},
+	}
+	for _, results := range runs {
+		if err := history.Append(results); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	// A line cut short by a crash is skipped
+	file, err := os.OpenFile(filepath.Join(dir, historyFileName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"run_id": "partial", "res`)
+	file.Close()
+
+	tests := []struct {
+		name     string
+		query    HistoryQuery
+		expected []string
+	}{
+		{name: "everything, oldest first", query: HistoryQuery{}, expected: []string{"may/small", "may/large", "june/small"}},
+		{name: "by sha prefix", query: HistoryQuery{SHA: "bbbb"}, expected: []string{"june/small"}},
+		{name: "by scenario", query: HistoryQuery{Scenario: "large"}, expected: []string{"may/large"}},
+		{name: "by ref and command", query: HistoryQuery{Ref: "main", Command: "Plan"}, expected: []string{"may/small", "may/large", "june/small"}},
+		{name: "by other command", query: HistoryQuery{Command: "Apply"}, expected: nil},
+		{name: "since", query: HistoryQuery{Since: june}, expected: []string{"june/small"}},
+		{name: "until", query: HistoryQuery{Until: june}, expected: []string{"may/small", "may/large"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := history.Query(tt.query)
+			if err != nil {
+				t.Fatalf("Query() error = %v", err)
+			}
+
+			var result []string
+			for _, entry := range entries {
+				result = append(result, entry.RunID+"/"+entry.Scenario())
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Query() = %v, want %v", result, tt.expected)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("Query() = %v, want %v", result, tt.expected)
+				}
+			}
+		})
+	}
+
+	entries, _ := history.Query(HistoryQuery{Scenario: "large"})
+	if entries[0].Hostname != "perf-runner-1" || !entries[0].Time.Equal(may) {
+		t.Errorf("unexpected run metadata %+v", entries[0])
+	}
+}
@@ -0,0 +1,309 @@
+package benchmark
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const (
+	htmlReportFileName = "report.html"
+
+	// Dimensions of the charts in pixels
+	chartLabelWidth = 280
+	chartBarWidth   = 560
+	chartRowHeight  = 26
+	chartPadding    = 30
+)
+
+// phaseColours are used in turn for each phase of the stacked bar chart
+var phaseColours = []string{"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"}
+
+// htmlReport writes a self-contained HTML page with a summary table, duration and phase charts, a breakdown of time
+// spent per resource type and links to the log files. Charts are inline SVG so the page works offline.
+type htmlReport struct{}
+
+// htmlReportData is the data rendered by htmlReportTemplate
+type htmlReportData struct {
+	Title     string
+	Generated string
+	Rows      []htmlReportRow
+	Durations htmlChart
+	Phases    htmlChart
+	Legend    []htmlLegendEntry
+	Resources []htmlResourceRow
+}
+
+// htmlReportRow is a result in the summary table
+type htmlReportRow struct {
+	Name   string
+	SHA    string
+	Stats  Statistics
+	Log    string
+	Failed bool
+}
+
+// htmlChart is a horizontal bar chart with one row per result
+type htmlChart struct {
+	Width  int
+	Height int
+	Bars   []htmlBar
+	Axis   []htmlTick
+}
+
+// htmlBar is a row of a bar chart, made up of one or more segments and an optional error bar
+type htmlBar struct {
+	Label    string
+	Y        int
+	Segments []htmlSegment
+	ErrorBar *htmlErrorBar
+}
+
+// htmlSegment is a rectangle of a bar
+type htmlSegment struct {
+	X      float64
+	Width  float64
+	Colour string
+	Title  string
+}
+
+// htmlErrorBar spans one standard deviation either side of the mean
+type htmlErrorBar struct {
+	X1, X2 float64
+}
+
+// htmlTick is a labelled position on a chart axis
+type htmlTick struct {
+	X     float64
+	Label string
+}
+
+// htmlLegendEntry names the colour of a phase
+type htmlLegendEntry struct {
+	Name   string
+	Colour string
+}
+
+// htmlResourceRow holds the mean seconds spent on a resource type by each result
+type htmlResourceRow struct {
+	ResourceType string
+	Seconds      []float64
+}
+
+// FileName returns the name of the HTML report
+func (htmlReport) FileName() string {
+	return htmlReportFileName
+}
+
+// Write renders the HTML report
+func (htmlReport) Write(w io.Writer, results *Results) error {
+	return htmlReportTemplate.Execute(w, newHTMLReportData(results.Results))
+}
+
+// newHTMLReportData lays out the tables and charts of the report
+func newHTMLReportData(data []PlanDetails) htmlReportData {
+	report := htmlReportData{
+		Title:     "Terraform provider benchmark",
+		Generated: time.Now().Format(time.RFC1123),
+	}
+	if len(data) > 0 {
+		report.Title = fmt.Sprintf("Terraform provider benchmark: %s", commandName(data[0].Command))
+	}
+
+	stats := make([]Statistics, len(data))
+	phases := make([][]Phase, len(data))
+	var maxDuration, maxPhases float64
+	for i, plan := range data {
+		stats[i] = summarise(commandDurations(plan))
+		maxDuration = max(maxDuration, stats[i].Mean+stats[i].StdDev, stats[i].Max)
+
+		phases[i] = meanPhases(plan)
+		var total float64
+		for _, phase := range phases[i] {
+			total += phase.Duration
+		}
+		maxPhases = max(maxPhases, total)
+
+		report.Rows = append(report.Rows, htmlReportRow{
+			Name:   resultName(plan),
+			SHA:    shortSHA(plan.SHA),
+			Stats:  stats[i],
+			Log:    plan.Log,
+			Failed: plan.Failed(),
+		})
+	}
+
+	report.Durations = newHTMLChart(len(data), maxDuration)
+	report.Phases = newHTMLChart(len(data), maxPhases)
+	colours := make(map[string]string)
+	for i, plan := range data {
+		scale := chartScale(maxDuration)
+		bar := &report.Durations.Bars[i]
+		bar.Label = resultName(plan)
+		bar.Segments = []htmlSegment{{
+			X:      chartLabelWidth,
+			Width:  stats[i].Mean * scale,
+			Colour: phaseColours[0],
+			Title:  fmt.Sprintf("%s: mean %.2fs ± %.2fs over %d iterations", bar.Label, stats[i].Mean, stats[i].StdDev, stats[i].N),
+		}}
+		if stats[i].StdDev > 0 {
+			bar.ErrorBar = &htmlErrorBar{
+				X1: chartLabelWidth + max(stats[i].Mean-stats[i].StdDev, 0)*scale,
+				X2: chartLabelWidth + (stats[i].Mean+stats[i].StdDev)*scale,
+			}
+		}
+
+		scale = chartScale(maxPhases)
+		bar = &report.Phases.Bars[i]
+		bar.Label = resultName(plan)
+		x := float64(chartLabelWidth)
+		for _, phase := range phases[i] {
+			colour, ok := colours[phase.Name]
+			if !ok {
+				colour = phaseColours[len(colours)%len(phaseColours)]
+				colours[phase.Name] = colour
+				report.Legend = append(report.Legend, htmlLegendEntry{Name: phase.Name, Colour: colour})
+			}
+			bar.Segments = append(bar.Segments, htmlSegment{
+				X:      x,
+				Width:  phase.Duration * scale,
+				Colour: colour,
+				Title:  fmt.Sprintf("%s: %.2fs", phase.Name, phase.Duration),
+			})
+			x += phase.Duration * scale
+		}
+	}
+
+	report.Resources = newHTMLResourceRows(data)
+	return report
+}
+
+// newHTMLChart returns a chart with a row for each result and an axis running from zero to maxValue seconds
+func newHTMLChart(rows int, maxValue float64) htmlChart {
+	chart := htmlChart{
+		Width:  chartLabelWidth + chartBarWidth + chartPadding,
+		Height: rows*chartRowHeight + chartPadding,
+		Bars:   make([]htmlBar, rows),
+	}
+	for i := range chart.Bars {
+		chart.Bars[i].Y = i * chartRowHeight
+	}
+
+	scale := chartScale(maxValue)
+	for i := 0; i <= 4; i++ {
+		seconds := maxValue * float64(i) / 4
+		chart.Axis = append(chart.Axis, htmlTick{X: chartLabelWidth + seconds*scale, Label: fmt.Sprintf("%.1fs", seconds)})
+	}
+	return chart
+}
+
+// chartScale returns the number of pixels per second for a chart whose longest bar is maxValue seconds
+func chartScale(maxValue float64) float64 {
+	if maxValue <= 0 {
+		return 0
+	}
+	return chartBarWidth / maxValue
+}
+
+// newHTMLResourceRows returns the mean seconds spent on each resource type by each result, slowest type first
+func newHTMLResourceRows(data []PlanDetails) []htmlResourceRow {
+	means := make([]map[string]float64, len(data))
+	totals := make(map[string]float64)
+	for i, plan := range data {
+		means[i] = meanResources(plan)
+		for resourceType, seconds := range means[i] {
+			totals[resourceType] += seconds
+		}
+	}
+
+	resourceTypes := make([]string, 0, len(totals))
+	for resourceType := range totals {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Slice(resourceTypes, func(i, j int) bool {
+		if totals[resourceTypes[i]] != totals[resourceTypes[j]] {
+			return totals[resourceTypes[i]] > totals[resourceTypes[j]]
+		}
+		return resourceTypes[i] < resourceTypes[j]
+	})
+
+	rows := make([]htmlResourceRow, len(resourceTypes))
+	for i, resourceType := range resourceTypes {
+		rows[i] = htmlResourceRow{ResourceType: resourceType, Seconds: make([]float64, len(data))}
+		for j := range data {
+			rows[i].Seconds[j] = means[j][resourceType]
+		}
+	}
+	return rows
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// writeHTMLReportToFile writes the HTML report to the run directory
+func (b *Benchmark) writeHTMLReportToFile(results *Results) error {
+	reportPath := filepath.Join(b.runDir, htmlReportFileName)
+	b.logMessage(LogLevelInfo, "Writing HTML report to %s", reportPath)
+	return writeResults(htmlReport{}, reportPath, results)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292f; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #d0d7de; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+tr.failed td:first-child { color: #cf222e; }
+code { font-size: 0.9em; }
+svg text { font-size: 12px; fill: #24292f; }
+.legend span { display: inline-block; margin-right: 1.5em; }
+.legend i { display: inline-block; width: 12px; height: 12px; margin-right: 4px; vertical-align: middle; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Generated {{.Generated}}</p>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Result</th><th>SHA</th><th>Iterations</th><th>Mean (s)</th><th>Median (s)</th><th>Std dev (s)</th><th>Min (s)</th><th>Max (s)</th><th>Log</th></tr>
+{{range .Rows}}<tr{{if .Failed}} class="failed"{{end}}><td>{{.Name}}</td><td><code>{{.SHA}}</code></td><td>{{.Stats.N}}</td><td>{{printf "%.2f" .Stats.Mean}}</td><td>{{printf "%.2f" .Stats.Median}}</td><td>{{printf "%.2f" .Stats.StdDev}}</td><td>{{printf "%.2f" .Stats.Min}}</td><td>{{printf "%.2f" .Stats.Max}}</td><td>{{if .Log}}<a href="{{.Log}}">log</a>{{end}}</td></tr>
+{{end}}</table>
+
+<h2>Command duration</h2>
+<p>Mean duration of the measured command, with error bars of one standard deviation.</p>
+{{template "chart" .Durations}}
+
+<h2>Phases</h2>
+<p>Setup phases followed by the mean duration of each phase per iteration.</p>
+<p class="legend">{{range .Legend}}<span><i style="background: {{.Colour}}"></i>{{.Name}}</span>{{end}}</p>
+{{template "chart" .Phases}}
+
+{{if .Resources}}<h2>Resource types</h2>
+<p>Mean seconds spent refreshing, creating, updating or deleting each resource type.</p>
+<table>
+<tr><th>Resource type</th>{{range .Rows}}<th>{{.Name}}</th>{{end}}</tr>
+{{range .Resources}}<tr><td><code>{{.ResourceType}}</code></td>{{range .Seconds}}<td>{{printf "%.2f" .}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+{{define "chart"}}<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .Bars}}<g transform="translate(0 {{.Y}})">
+<text x="0" y="17">{{.Label}}</text>
+{{range .Segments}}<rect x="{{printf "%.1f" .X}}" y="4" width="{{printf "%.1f" .Width}}" height="18" fill="{{.Colour}}"><title>{{.Title}}</title></rect>
+{{end}}{{with .ErrorBar}}<line x1="{{printf "%.1f" .X1}}" y1="13" x2="{{printf "%.1f" .X2}}" y2="13" stroke="#24292f"/><line x1="{{printf "%.1f" .X1}}" y1="8" x2="{{printf "%.1f" .X1}}" y2="18" stroke="#24292f"/><line x1="{{printf "%.1f" .X2}}" y1="8" x2="{{printf "%.1f" .X2}}" y2="18" stroke="#24292f"/>
+{{end}}</g>
+{{end}}{{range .Axis}}<text x="{{printf "%.1f" .X}}" y="{{$.Height}}" text-anchor="middle" dy="-4">{{.Label}}</text>
+{{end}}</svg>{{end}}`))
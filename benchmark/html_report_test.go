@@ -0,0 +1,55 @@
+package benchmark
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHTMLReport_Write(t *testing.T) {
+	data := testResults()
+	data[0].Command = []string{"terraform", "plan"}
+	data[0].Log = "logs/small-org/main_log-DEBUG_parallelism-10.log"
+	data[0].Samples[0].Resources = map[string]float64{"genesyscloud_user": 1.5}
+	data[0].Samples[1].Resources = map[string]float64{"genesyscloud_user": 0.5}
+	data = append(data, PlanDetails{Version: "<script>", Duration: 1})
+
+	var buf bytes.Buffer
+	if err := (htmlReport{}).Write(&buf, &Results{Results: data}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"Terraform provider benchmark: Plan",
+		`<a href="logs/small-org/main_log-DEBUG_parallelism-10.log">log</a>`,
+		"main small-org [log=DEBUG,parallelism=10]",
+		"<svg",
+		"<line", // error bar
+		"checkout",
+		"<code>genesyscloud_user</code></td><td>1.00</td>",
+		"&lt;script&gt;",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected report to contain %q", expected)
+		}
+	}
+
+	if strings.Contains(output, "<script>") || strings.Contains(output, "http://") && !strings.Contains(output, "http://www.w3.org/2000/svg") {
+		t.Error("expected report to be self-contained and escaped")
+	}
+}
+
+func TestMeanPhases(t *testing.T) {
+	phases := meanPhases(testResults()[0])
+
+	if len(phases) != 2 {
+		t.Fatalf("meanPhases() = %+v, want setup and command phases", phases)
+	}
+	if phases[0].Name != phaseCheckout || phases[0].Duration != 1 {
+		t.Errorf("phases[0] = %+v, want checkout of 1s", phases[0])
+	}
+	if phases[1].Name != phaseCommand || phases[1].Duration != 2.25 {
+		t.Errorf("phases[1] = %+v, want mean command of 2.25s", phases[1])
+	}
+}
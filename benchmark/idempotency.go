@@ -0,0 +1,132 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// detailedExitCodeChanges is the exit code of terraform plan -detailed-exitcode when the plan is not empty
+const detailedExitCodeChanges = 2
+
+// idempotencyFlags are the flags of the measured apply, with whether they take a value, that change what terraform
+// plan compares against and so are passed on to the plan checking idempotency
+var idempotencyFlags = map[string]bool{
+	"target":       true,
+	"exclude":      true,
+	"refresh":      false,
+	"var":          true,
+	"var-file":     true,
+	"parallelism":  true,
+	"lock":         false,
+	"lock-timeout": true,
+}
+
+// idempotencyArgs returns the arguments in args that the plan checking idempotency needs to run under the same
+// conditions as the apply, in either the -flag=value or -flag value form
+func idempotencyArgs(args []string) []string {
+	var planArgs []string
+	for i := 0; i < len(args); i++ {
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		takesValue, ok := idempotencyFlags[name]
+		if !ok {
+			continue
+		}
+		planArgs = append(planArgs, args[i])
+		if takesValue && !hasValue && i+1 < len(args) {
+			i++
+			planArgs = append(planArgs, args[i])
+		}
+	}
+	return planArgs
+}
+
+// idempotencyCommand returns the plan run after a measured apply, with the matrix cell's flags and the arguments of
+// the apply that affect what is planned
+func (b *Benchmark) idempotencyCommand(ws *workspace, r run) []string {
+	command := append(b.buildCommand(ws, Plan, false), r.cell.args()...)
+	command = append(command, idempotencyArgs(b.Args)...)
+	return append(command, "-detailed-exitcode", "-json", "-input=false")
+}
+
+// checkIdempotency runs terraform plan after a measured apply and records the changes it still proposes. The plan's
+// exit code decides whether it was empty, as changes to outputs or drift are not reported as resource changes.
+func (b *Benchmark) checkIdempotency(ws *workspace, r run) (*IdempotencyDetails, error) {
+	command := b.idempotencyCommand(ws, r)
+	outputPath := ws.idempotencyLogFilePath(r)
+	b.logMessage(LogLevelInfo, "🔁 Checking idempotency of reference %s", r)
+
+	planErr := b.runSetupCommand(ws, command, outputPath, r.cell.env()...)
+	var exitErr *exec.ExitError
+	if planErr != nil && !(errors.As(planErr, &exitErr) && exitErr.ExitCode() == detailedExitCodeChanges) {
+		return nil, fmt.Errorf("idempotency plan failed: %v", planErr)
+	}
+
+	outputFile, err := os.Open(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer outputFile.Close()
+
+	changes, err := parsePlannedChanges(outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read idempotency plan: %w", err)
+	}
+
+	details := &IdempotencyDetails{Empty: planErr == nil, PendingChanges: len(changes)}
+	for _, change := range changes {
+		details.Addresses = append(details.Addresses, change.Address)
+	}
+
+	if details.Empty {
+		b.logMessage(LogLevelInfo, "🔁 Plan after apply is empty for reference %s", r)
+	} else {
+		b.logMessage(LogLevelInfo, "⚠️ Plan after apply has %d pending changes for reference %s: %s", details.PendingChanges, r, strings.Join(details.Addresses, ", "))
+	}
+	return details, nil
+}
+
+// idempotencyLogFilePath returns the log of the plan checking idempotency in an iteration of a run, e.g.
+// main_idempotency_2.log
+func (ws *workspace) idempotencyLogFilePath(r run) string {
+	return ws.iterationLogFilePath(r, "idempotency")
+}
+
+// perpetualDiffs returns a description of every result whose apply was followed by a non-empty plan
+func perpetualDiffs(data []PlanDetails) []string {
+	var diffs []string
+	for _, plan := range data {
+		if plan.Idempotency == nil || plan.Idempotency.Empty {
+			continue
+		}
+
+		description := plan.Version
+		if plan.Config != "" {
+			description += " on " + plan.Config
+		}
+		if len(plan.Cell) > 0 {
+			description += fmt.Sprintf(" %v", plan.Cell)
+		}
+		diffs = append(diffs, fmt.Sprintf("%s (%d pending changes)", description, plan.Idempotency.PendingChanges))
+	}
+	return diffs
+}
+
+// logIdempotencySummary logs the references that left a perpetual diff
+func (b *Benchmark) logIdempotencySummary(data []PlanDetails) {
+	diffs := perpetualDiffs(data)
+	if len(diffs) == 0 {
+		b.logMessage(LogLevelInfo, "🔁 Every apply was idempotent")
+		return
+	}
+
+	b.logMessage(LogLevelInfo, "⚠️ %d applies were not idempotent:", len(diffs))
+	for _, diff := range diffs {
+		b.logMessage(LogLevelInfo, "⚠️   %s", diff)
+	}
+}
@@ -0,0 +1,84 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchmark_idempotencyCommand(t *testing.T) {
+	b := &Benchmark{
+		TfCommand: Apply,
+		Args:      []string{"-refresh=false", "-target", "genesyscloud_user.alice", "-compact-warnings", "--target=genesyscloud_user.bob", "-replace=genesyscloud_user.carol"},
+		Variables: map[string]string{"region": "us-east-1"},
+		Matrix:    []MatrixAxis{{Name: "parallelism", Flag: "-parallelism", Values: []string{"10"}}},
+	}
+	r := run{reference: "main", cell: b.matrixCells()[0]}
+
+	// -replace would be planned again after every apply, and -compact-warnings does not affect the plan
+	expected := "terraform plan -var=region=us-east-1 -parallelism=10 -refresh=false -target genesyscloud_user.alice --target=genesyscloud_user.bob -detailed-exitcode -json -input=false"
+	if command := strings.Join(b.idempotencyCommand(&workspace{}, r), " "); command != expected {
+		t.Errorf("idempotencyCommand() = %v, want %v", command, expected)
+	}
+}
+
+func TestPerpetualDiffs(t *testing.T) {
+	data := []PlanDetails{
+		{Version: "v1.66.0", Idempotency: &IdempotencyDetails{Empty: true}},
+		{Version: "main", Config: "large", Idempotency: &IdempotencyDetails{PendingChanges: 2, Addresses: []string{"a.b", "c.d"}}},
+		{Version: "feature", Cell: map[string]string{"parallelism": "10"}, Idempotency: &IdempotencyDetails{PendingChanges: 1}},
+		{Version: "unchecked"},
+	}
+
+	diffs := perpetualDiffs(data)
+	expected := []string{
+		"main on large (2 pending changes)",
+		"feature map[parallelism:10] (1 pending changes)",
+	}
+	if len(diffs) != len(expected) {
+		t.Fatalf("perpetualDiffs() = %v, want %v", diffs, expected)
+	}
+	for i := range expected {
+		if diffs[i] != expected[i] {
+			t.Errorf("perpetualDiffs()[%d] = %v, want %v", i, diffs[i], expected[i])
+		}
+	}
+}
+
+func TestBenchmark_checkIdempotency(t *testing.T) {
+	// The plan proposes no resource changes, and exits with the code set for the iteration
+	installFakeCommand(t, "terraform", `echo '{"type":"version"}'
+exit "$(cat exit_code)"
+`)
+	b := &Benchmark{TfCommand: Apply, CheckIdempotency: true}
+	ws := &workspace{dir: t.TempDir(), logsDir: t.TempDir()}
+
+	tests := []struct {
+		iteration int
+		exitCode  string
+		empty     bool
+	}{
+		{iteration: 1, exitCode: "0", empty: true},
+		{iteration: 2, exitCode: "2", empty: false},
+	}
+	for _, tt := range tests {
+		if err := os.WriteFile(filepath.Join(ws.dir, "exit_code"), []byte(tt.exitCode), 0644); err != nil {
+			t.Fatal(err)
+		}
+		details, err := b.checkIdempotency(ws, run{reference: "main", iteration: tt.iteration})
+		if err != nil {
+			t.Fatalf("checkIdempotency() error = %v", err)
+		}
+		if details.Empty != tt.empty || details.PendingChanges != 0 {
+			t.Errorf("checkIdempotency() = %+v in iteration %d, want empty %v", details, tt.iteration, tt.empty)
+		}
+	}
+
+	// Each iteration keeps its own log
+	for _, name := range []string{"main_idempotency_1.log", "main_idempotency_2.log"} {
+		if _, err := os.Stat(filepath.Join(ws.logsDir, name)); err != nil {
+			t.Errorf("log %s was not written: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package benchmark
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JUnitWriter writes a JUnit XML report to junit.xml with a test suite per configuration and matrix cell and a test
+// case per reference. Failed steps, regressions beyond MaxRegressionPercent, perpetual diffs and plan differences are
+// reported as test failures.
+type JUnitWriter struct{}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+
+	time float64
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// FileName returns the name of the JUnit report
+func (JUnitWriter) FileName() string {
+	return "junit.xml"
+}
+
+// Write writes the JUnit report. The time of each test case is the mean duration of the measured command.
+func (JUnitWriter) Write(w io.Writer, results *Results) error {
+	data := results.Results
+	report := junitTestSuites{Name: "terraform-provider-benchmark"}
+	suites := make(map[string]int)
+
+	var total float64
+	for _, plan := range data {
+		name := "Benchmark" + commandName(plan.Command)
+		if scenario := scenarioName(plan); scenario != "" {
+			name += " " + scenario
+		}
+
+		index, ok := suites[name]
+		if !ok {
+			index = len(report.Suites)
+			suites[name] = index
+			report.Suites = append(report.Suites, junitTestSuite{Name: name})
+		}
+		suite := &report.Suites[index]
+		if suite.Timestamp == "" && len(plan.Setup) > 0 {
+			suite.Timestamp = plan.Setup[0].Start.Format("2006-01-02T15:04:05")
+		}
+
+		stats := summarise(commandDurations(plan))
+		testCase := junitTestCase{
+			Name:      plan.Version,
+			ClassName: name,
+			Time:      fmt.Sprintf("%.3f", plan.Duration),
+			Failure:   junitFailureFor(plan),
+			SystemOut: fmt.Sprintf("sha: %s\niterations: %d\nmedian: %.3fs\nstddev: %.3fs\nlog: %s\n", plan.SHA, stats.N, stats.Median, stats.StdDev, plan.Log),
+		}
+
+		suite.Cases = append(suite.Cases, testCase)
+		suite.Tests++
+		report.Tests++
+		if testCase.Failure != nil {
+			suite.Failures++
+			report.Failures++
+		}
+		suite.time += plan.Duration
+		total += plan.Duration
+	}
+
+	for i := range report.Suites {
+		report.Suites[i].Time = fmt.Sprintf("%.3f", report.Suites[i].time)
+	}
+	report.Time = fmt.Sprintf("%.3f", total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitFailureFor returns the failure of a result, or nil if it passed. A result can only have one failure element, so
+// every problem is listed in its message and text.
+func junitFailureFor(plan PlanDetails) *junitFailure {
+	var types, messages, text []string
+
+	if plan.Failure != nil {
+		types = append(types, plan.Failure.Phase)
+		messages = append(messages, failureMessage(plan.Failure))
+		if plan.Failure.LogTail != "" {
+			text = append(text, fmt.Sprintf("Last lines of %s:\n%s", plan.Failure.Log, plan.Failure.LogTail))
+		}
+	}
+	if plan.Regression != nil {
+		types = append(types, "regression")
+		messages = append(messages, fmt.Sprintf("%.1f%% slower than %s (p=%.3f)", plan.Regression.Delta, plan.Regression.Baseline, plan.Regression.P))
+	}
+	if plan.Idempotency != nil && !plan.Idempotency.Empty {
+		types = append(types, "idempotency")
+		messages = append(messages, fmt.Sprintf("plan after apply proposed %d %s", plan.Idempotency.PendingChanges, plural(plan.Idempotency.PendingChanges, "change")))
+		text = append(text, "Pending changes:\n"+strings.Join(plan.Idempotency.Addresses, "\n"))
+	}
+	if len(plan.PlanDifferences) > 0 {
+		types = append(types, "plan-equivalence")
+		messages = append(messages, "plan differs from the baseline")
+		text = append(text, "Plan differences:\n"+strings.Join(plan.PlanDifferences, "\n"))
+	}
+
+	if len(types) == 0 {
+		return nil
+	}
+	return &junitFailure{
+		Message: strings.Join(messages, "; "),
+		Type:    strings.Join(types, ","),
+		Text:    strings.Join(text, "\n\n"),
+	}
+}
@@ -0,0 +1,65 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestJUnitWriter_Write(t *testing.T) {
+	data := []PlanDetails{
+		{Version: "main", Command: []string{"terraform", "plan"}, Config: "small", Duration: 10, Samples: samplesOf(10)},
+		{
+			Version:  "feature",
+			Command:  []string{"terraform", "plan"},
+			Config:   "small",
+			Duration: 12,
+			Samples:  samplesOf(12),
+			Failure:  &Failure{Phase: phaseCommand, Iteration: 2, Error: "terraform command failed: exit status 1", Log: "logs/small/feature.log", LogTail: "Error: Provider produced inconsistent result"},
+		},
+		{
+			Version:    "slow",
+			Command:    []string{"terraform", "plan"},
+			Config:     "large",
+			Duration:   20,
+			Samples:    samplesOf(20),
+			Regression: &RegressionDetails{Baseline: "main", Delta: 25, P: 0.008},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (JUnitWriter{}).Write(&buf, &Results{Results: data}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	var report junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to parse report: %v\n%s", err, buf.String())
+	}
+
+	if report.Tests != 3 || report.Failures != 2 || len(report.Suites) != 2 {
+		t.Fatalf("expected 3 tests, 2 failures and 2 suites, got %d, %d and %d", report.Tests, report.Failures, len(report.Suites))
+	}
+
+	small := report.Suites[0]
+	if small.Name != "BenchmarkPlan small" || small.Tests != 2 || small.Failures != 1 || small.Time != "22.000" {
+		t.Errorf("unexpected suite %+v", small)
+	}
+	if small.Cases[0].Failure != nil || small.Cases[0].Time != "10.000" {
+		t.Errorf("expected main to pass in 10s, got %+v", small.Cases[0])
+	}
+
+	failure := small.Cases[1].Failure
+	if failure == nil || failure.Type != phaseCommand || failure.Message != "command failed in iteration 2: terraform command failed: exit status 1" {
+		t.Fatalf("unexpected failure %+v", failure)
+	}
+	if !strings.Contains(failure.Text, "Last lines of logs/small/feature.log:\nError: Provider produced inconsistent result") {
+		t.Errorf("expected log excerpt in failure, got %q", failure.Text)
+	}
+
+	regression := report.Suites[1].Cases[0].Failure
+	if regression == nil || regression.Type != "regression" || regression.Message != "25.0% slower than main (p=0.008)" {
+		t.Errorf("unexpected regression failure %+v", regression)
+	}
+}
@@ -0,0 +1,136 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const manifestFileName = "manifest.json"
+
+// manifest is written to the run directory, mapping the log files written for each reference back to it, since log
+// file names are made safe for the file system and cannot always be read as the reference
+type manifest struct {
+	RunID string        `json:"run_id,omitempty"`
+	Logs  []manifestLog `json:"logs"`
+}
+
+// manifestLog describes a log file written for a reference
+type manifestLog struct {
+	// File is the path of the log file, relative to the run directory
+	File string `json:"file"`
+
+	// Phase is the phase that wrote the log, e.g. checkout or command, or destroy for the destroy tearing down a
+	// reference's temporary workspace
+	Phase string `json:"phase"`
+
+	Reference string `json:"reference"`
+
+	// Config and Cell are omitted for logs shared by every configuration or matrix cell of the reference
+	Config string            `json:"config,omitempty"`
+	Cell   map[string]string `json:"cell,omitempty"`
+
+	// Iteration is set for logs written for a single iteration
+	Iteration int `json:"iteration,omitempty"`
+
+	// Seed is set for logs written while seeding state with the reference, see SeedReference
+	Seed bool `json:"seed,omitempty"`
+}
+
+const (
+	// teardownPhase names the destroy tearing down a reference's temporary workspace in the manifest
+	teardownPhase = "destroy"
+
+	// seedPhase names the apply seeding state in the manifest
+	seedPhase = "seed"
+)
+
+// newManifest lists the log files of every reference, configuration, matrix cell and iteration that were written
+func (b *Benchmark) newManifest() manifest {
+	m := manifest{RunID: b.runID, Logs: []manifestLog{}}
+	seen := make(map[string]bool)
+	add := func(path string, log manifestLog) {
+		if path == "" || seen[path] {
+			return
+		}
+		seen[path] = true
+		if _, err := os.Stat(path); err != nil {
+			return
+		}
+		log.File = b.relativeOutputPath(path)
+		m.Logs = append(m.Logs, log)
+	}
+
+	if b.SeedReference != "" {
+		logName := seedLogName(b.SeedReference)
+		for _, phase := range []string{phaseCheckout, phaseBuild} {
+			add(b.stepLogFilePath(logName, phase), manifestLog{Phase: phase, Reference: b.SeedReference, Seed: true})
+		}
+		for _, config := range b.tfConfigs() {
+			ws := &workspace{config: config, logsDir: b.configLogsDir(config), logName: logName}
+			for _, log := range []struct{ phase, path string }{
+				{phaseInit, ws.logFilePath(logFileName(logName + "_init"))},
+				{seedPhase, ws.logFilePath(seedLogFileName)},
+				{teardownPhase, ws.destroyLogFilePath()},
+				{teardownPhase, previewLogFilePath(ws.destroyLogFilePath())},
+			} {
+				add(log.path, manifestLog{Phase: log.phase, Reference: b.SeedReference, Config: config.Name, Seed: true})
+			}
+		}
+	}
+
+	for _, reference := range b.References {
+		r := run{reference: reference}
+		for _, phase := range []string{phaseCheckout, phaseBuild} {
+			add(b.phaseLogFilePath(nil, r, phase), manifestLog{Phase: phase, Reference: reference})
+		}
+
+		for _, config := range b.tfConfigs() {
+			ws := &workspace{config: config, logsDir: b.configLogsDir(config), logName: referenceLogName(reference)}
+			if b.SeedReference != "" {
+				// Only whether there is a snapshot matters for naming the logs restoring it
+				ws.snapshot = b.SeedReference
+			}
+			add(b.phaseLogFilePath(ws, r, phaseInit), manifestLog{Phase: phaseInit, Reference: reference, Config: config.Name})
+
+			for _, cell := range b.matrixCells() {
+				r.cell = cell
+				add(b.phaseLogFilePath(ws, r, phaseShow), manifestLog{Phase: phaseShow, Reference: reference, Config: config.Name, Cell: cell.values()})
+				for iteration := 1; iteration <= b.iterations(); iteration++ {
+					r.iteration = iteration
+					prepare := manifestLog{Phase: phasePrepare, Reference: reference, Config: config.Name, Cell: cell.values(), Iteration: iteration}
+					add(b.prepareLogFilePath(ws, r), prepare)
+					if ws.snapshot == "" && b.TfCommand.spec().preRun == Destroy {
+						add(previewLogFilePath(b.prepareLogFilePath(ws, r)), prepare)
+					}
+					if b.TfCommand == Destroy {
+						add(ws.commandPreviewLogFilePath(r), prepare)
+					}
+					add(ws.commandLogFilePath(r), manifestLog{Phase: phaseCommand, Reference: reference, Config: config.Name, Cell: cell.values(), Iteration: iteration})
+					add(ws.idempotencyLogFilePath(r), manifestLog{Phase: phaseIdempotency, Reference: reference, Config: config.Name, Cell: cell.values(), Iteration: iteration})
+				}
+				r.iteration = 0
+			}
+
+			add(ws.destroyLogFilePath(), manifestLog{Phase: teardownPhase, Reference: reference, Config: config.Name})
+			add(previewLogFilePath(ws.destroyLogFilePath()), manifestLog{Phase: teardownPhase, Reference: reference, Config: config.Name})
+		}
+	}
+	return m
+}
+
+// writeManifest writes the manifest of log files to the run directory
+func (b *Benchmark) writeManifest() error {
+	path := filepath.Join(b.runDir, manifestFileName)
+	b.logMessage(LogLevelDebug, "Writing manifest to %s", path)
+
+	data, err := json.MarshalIndent(b.newManifest(), "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
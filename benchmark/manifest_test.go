@@ -0,0 +1,145 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestBenchmark_writeManifest(t *testing.T) {
+	// An absolute OutputDir is used as it is, not joined to the working directory
+	outputDir := filepath.Join(t.TempDir(), "output")
+	b := &Benchmark{
+		OutputDir:        outputDir,
+		References:       []string{"main", "feature/fast-plan"},
+		Matrix:           []MatrixAxis{{Name: "parallelism", Flag: "-parallelism", Values: []string{"10"}}},
+		CheckIdempotency: true,
+		runID:            "0a1b2c3d",
+	}
+	b.configureOutputPaths()
+	if !strings.HasPrefix(b.runDir, outputDir+string(filepath.Separator)) {
+		t.Fatalf("runDir = %v, want a directory in %v", b.runDir, outputDir)
+	}
+	if err := b.createOutputDirectories(); err != nil {
+		t.Fatalf("createOutputDirectories() error = %v", err)
+	}
+	// Only feature/fast-plan ran, so main has no logs to list
+	for _, name := range []string{
+		"feature_fast-plan-2c3e5ffa_init.log",
+		"feature_fast-plan-2c3e5ffa_parallelism-10_1.log",
+		"feature_fast-plan-2c3e5ffa_parallelism-10_idempotency_1.log",
+	} {
+		if err := os.WriteFile(filepath.Join(b.logsDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := b.writeManifest(); err != nil {
+		t.Fatalf("writeManifest() error = %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(outputDir, latestRunLinkName, manifestFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("failed to parse manifest: %v", err)
+	}
+
+	expected := []string{
+		"logs/feature_fast-plan-2c3e5ffa_init.log init feature/fast-plan ",
+		"logs/feature_fast-plan-2c3e5ffa_parallelism-10_1.log command feature/fast-plan parallelism=10",
+		"logs/feature_fast-plan-2c3e5ffa_parallelism-10_idempotency_1.log idempotency feature/fast-plan parallelism=10",
+	}
+	if m.RunID != "0a1b2c3d" || len(m.Logs) != len(expected) {
+		t.Fatalf("manifest = %+v, want %d logs of run 0a1b2c3d", m, len(expected))
+	}
+	for i, log := range m.Logs {
+		if actual := strings.Join([]string{log.File, log.Phase, log.Reference, formatCell(log.Cell)}, " "); actual != expected[i] {
+			t.Errorf("log %d = %q, want %q", i, actual, expected[i])
+		}
+	}
+}
+
+func TestBenchmark_newManifest_referenceAndIterationLogs(t *testing.T) {
+	b := &Benchmark{
+		OutputDir:  t.TempDir(),
+		References: []string{"main"},
+		TfCommand:  Apply,
+		Iterations: 2,
+		runID:      "0a1b2c3d",
+	}
+	b.configureOutputPaths()
+	if err := b.createOutputDirectories(); err != nil {
+		t.Fatalf("createOutputDirectories() error = %v", err)
+	}
+	for _, name := range []string{
+		"main_checkout.log", "main_build.log", "main_1.log", "main_destroy_2.log", "main_destroy_2_preview.log", "main_2.log", "main_destroy.log",
+	} {
+		if err := os.WriteFile(filepath.Join(b.logsDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		"logs/main_checkout.log checkout 0",
+		"logs/main_build.log build 0",
+		"logs/main_1.log command 1",
+		"logs/main_destroy_2.log prepare 2",
+		"logs/main_destroy_2_preview.log prepare 2",
+		"logs/main_2.log command 2",
+		"logs/main_destroy.log destroy 0",
+	}
+	m := b.newManifest()
+	if len(m.Logs) != len(expected) {
+		t.Fatalf("manifest = %+v, want %d logs", m, len(expected))
+	}
+	for i, log := range m.Logs {
+		if actual := strings.Join([]string{log.File, log.Phase, strconv.Itoa(log.Iteration)}, " "); actual != expected[i] {
+			t.Errorf("log %d = %q, want %q", i, actual, expected[i])
+		}
+	}
+}
+
+func TestBenchmark_newManifest_seedLogs(t *testing.T) {
+	b := &Benchmark{
+		OutputDir:     t.TempDir(),
+		References:    []string{"main"},
+		TfCommand:     Plan,
+		SeedReference: "main",
+		runID:         "0a1b2c3d",
+	}
+	b.configureOutputPaths()
+	if err := b.createOutputDirectories(); err != nil {
+		t.Fatalf("createOutputDirectories() error = %v", err)
+	}
+	for _, name := range []string{
+		"seed_main_build.log", "seed_main_init.log", "seed.log", "seed_main_destroy.log", "main_build.log", "main_restore_1.log", "main_1.log",
+	} {
+		if err := os.WriteFile(filepath.Join(b.logsDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []string{
+		"logs/seed_main_build.log build true",
+		"logs/seed_main_init.log init true",
+		"logs/seed.log seed true",
+		"logs/seed_main_destroy.log destroy true",
+		"logs/main_build.log build false",
+		"logs/main_restore_1.log prepare false",
+		"logs/main_1.log command false",
+	}
+	m := b.newManifest()
+	if len(m.Logs) != len(expected) {
+		t.Fatalf("manifest = %+v, want %d logs", m, len(expected))
+	}
+	for i, log := range m.Logs {
+		if actual := strings.Join([]string{log.File, log.Phase, strconv.FormatBool(log.Seed)}, " "); actual != expected[i] {
+			t.Errorf("log %d = %q, want %q", i, actual, expected[i])
+		}
+	}
+}
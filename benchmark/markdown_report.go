@@ -0,0 +1,270 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	markdownReportFileName = "report.md"
+
+	// defaultMarkdownReportMaxBytes is the maximum length of a GitHub pull request comment
+	defaultMarkdownReportMaxBytes = 65536
+
+	// markdownTruncationReserve is the space kept free for the note added when the report is truncated
+	markdownTruncationReserve = 256
+
+	// minSignificantIterations is the fewest iterations of both references for which the Mann-Whitney U test can
+	// report a p-value below significanceLevel
+	minSignificantIterations = 4
+)
+
+// markdownReport writes a summary of the results compared with the baseline reference, suitable for posting as a pull
+// request comment. Sections are omitted, and if necessary table rows, to keep the report within maxBytes.
+type markdownReport struct {
+	baseline string
+	maxBytes int
+}
+
+// FileName returns the name of the Markdown report
+func (markdownReport) FileName() string {
+	return markdownReportFileName
+}
+
+// Write renders the Markdown report
+func (m markdownReport) Write(w io.Writer, results *Results) error {
+	data := results.Results
+	maxBytes := m.maxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMarkdownReportMaxBytes
+	}
+
+	comparisons := make(map[string]Comparison)
+	var regressions, improvements int
+	tooFewIterations := false
+	for _, c := range compareResults(data, m.baseline) {
+		comparisons[comparisonKey(c.Result)] = c
+		if c.Regression() {
+			regressions++
+		}
+		if c.Improvement() {
+			improvements++
+		}
+		if c.Old.N < minSignificantIterations || c.New.N < minSignificantIterations {
+			tooFewIterations = true
+		}
+	}
+
+	var report strings.Builder
+	title := "Terraform provider benchmark"
+	if len(data) > 0 {
+		title += ": " + commandName(data[0].Command)
+	}
+	fmt.Fprintf(&report, "## %s\n\n", title)
+	fmt.Fprintf(&report, "Compared with baseline `%s`: %d %s, %d %s.\n\n",
+		m.baseline, regressions, plural(regressions, "regression"), improvements, plural(improvements, "improvement"))
+	report.WriteString("| | Result | Median (s) | Δ | p |\n|---|---|---:|---:|---:|\n")
+
+	var rows []string
+	for _, plan := range data {
+		rows = append(rows, markdownRow(plan, m.baseline, comparisons))
+	}
+
+	var notes []string
+	notes = append(notes, fmt.Sprintf("_Medians ± standard deviation of the measured command. p-values are from a two-sided Mann-Whitney U test and `*` marks p < %g._", significanceLevel))
+	if tooFewIterations {
+		notes = append(notes, fmt.Sprintf("_Differences can only be significant with at least %d iterations of each reference._", minSignificantIterations))
+	}
+
+	var sections []string
+	if failures := markdownFailures(data); failures != "" {
+		sections = append(sections, failures)
+	}
+	for _, plan := range data {
+		if resources := markdownResources(plan, m.baseline, comparisons); resources != "" {
+			sections = append(sections, resources)
+		}
+	}
+
+	omittedRows, omittedSections := 0, 0
+	for i, row := range rows {
+		if report.Len()+len(row)+markdownTruncationReserve > maxBytes {
+			omittedRows = len(rows) - i
+			break
+		}
+		report.WriteString(row)
+	}
+	report.WriteString("\n")
+	for _, note := range notes {
+		if omittedRows == 0 && report.Len()+len(note)+2+markdownTruncationReserve <= maxBytes {
+			report.WriteString(note + "\n\n")
+		}
+	}
+	for _, section := range sections {
+		if omittedRows > 0 || report.Len()+len(section)+markdownTruncationReserve > maxBytes {
+			omittedSections++
+			continue
+		}
+		report.WriteString(section)
+	}
+
+	if omittedRows > 0 || omittedSections > 0 {
+		fmt.Fprintf(&report, "_Report truncated: %d %s and %d %s omitted. See data.json for the full results._\n",
+			omittedRows, plural(omittedRows, "row"), omittedSections, plural(omittedSections, "section"))
+	}
+
+	_, err := io.WriteString(w, report.String())
+	return err
+}
+
+// markdownRow returns the table row of a result
+func markdownRow(plan PlanDetails, baseline string, comparisons map[string]Comparison) string {
+	stats := summarise(commandDurations(plan))
+	median := fmt.Sprintf("%.2f ± %.2f", stats.Median, stats.StdDev)
+	name := markdownEscape(resultName(plan))
+
+	if plan.Version == baseline {
+		return fmt.Sprintf("| | %s (baseline) | %s | | |\n", name, median)
+	}
+
+	c, ok := comparisons[comparisonKey(plan)]
+	if !ok {
+		return fmt.Sprintf("| | %s | %s | no baseline | |\n", name, median)
+	}
+
+	emoji, delta, p := markdownComparison(c)
+	return fmt.Sprintf("| %s | %s | %s | %s | %s |\n", emoji, name, median, delta, p)
+}
+
+// markdownComparison returns the status emoji, change and p-value cells of a comparison
+func markdownComparison(c Comparison) (emoji, delta, p string) {
+	emoji, p = "⚪", "n/a"
+	switch {
+	case c.Regression():
+		emoji = "🔴"
+	case c.Improvement():
+		emoji = "🟢"
+	}
+	if !math.IsNaN(c.P) {
+		p = fmt.Sprintf("%.3f", c.P)
+		if c.Significant() {
+			p += " *"
+		}
+	}
+	return emoji, fmt.Sprintf("%+.1f%%", c.Delta), p
+}
+
+// markdownFailures returns a collapsible section listing perpetual diffs and plan differences, or "" if there are none
+func markdownFailures(data []PlanDetails) string {
+	var failures []string
+	for _, plan := range data {
+		name := markdownEscape(resultName(plan))
+		if plan.Failure != nil {
+			failure := fmt.Sprintf("- **%s**: %s", name, markdownEscape(failureMessage(plan.Failure)))
+			if plan.Failure.LogTail != "" {
+				tail := strings.ReplaceAll(plan.Failure.LogTail, "```", "` ` `")
+				failure += "\n\n  ```text\n" + indent(tail, "  ") + "\n  ```"
+			}
+			failures = append(failures, failure)
+		}
+		if plan.Regression != nil {
+			failures = append(failures, fmt.Sprintf("- **%s**: %.1f%% slower than %s (p=%.3f)",
+				name, plan.Regression.Delta, markdownEscape(plan.Regression.Baseline), plan.Regression.P))
+		}
+		if plan.Idempotency != nil && !plan.Idempotency.Empty {
+			failures = append(failures, fmt.Sprintf("- **%s**: plan after apply proposed %d %s: %s",
+				name, plan.Idempotency.PendingChanges, plural(plan.Idempotency.PendingChanges, "change"), markdownCode(plan.Idempotency.Addresses)))
+		}
+		if len(plan.PlanDifferences) > 0 {
+			failures = append(failures, fmt.Sprintf("- **%s**: plan differs from the baseline: %s", name, markdownCode(plan.PlanDifferences)))
+		}
+	}
+	if len(failures) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("<details>\n<summary>⚠️ Failures (%d)</summary>\n\n%s\n\n</details>\n\n", len(failures), strings.Join(failures, "\n"))
+}
+
+// markdownResources returns a collapsible section with the time spent on each resource type by a result compared
+// with the baseline, or "" if no resource timings were recorded
+func markdownResources(plan PlanDetails, baseline string, comparisons map[string]Comparison) string {
+	means := meanResources(plan)
+	if len(means) == 0 {
+		return ""
+	}
+
+	var baselineMeans map[string]float64
+	if c, ok := comparisons[comparisonKey(plan)]; ok {
+		baselineMeans = meanResources(c.Baseline)
+	}
+
+	resourceTypes := make([]string, 0, len(means))
+	for resourceType := range means {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Slice(resourceTypes, func(i, j int) bool {
+		if means[resourceTypes[i]] != means[resourceTypes[j]] {
+			return means[resourceTypes[i]] > means[resourceTypes[j]]
+		}
+		return resourceTypes[i] < resourceTypes[j]
+	})
+
+	var section strings.Builder
+	fmt.Fprintf(&section, "<details>\n<summary>Resource types: %s</summary>\n\n", markdownEscape(resultName(plan)))
+	if baselineMeans != nil {
+		fmt.Fprintf(&section, "| Resource type | %s (s) | Mean (s) | Δ |\n|---|---:|---:|---:|\n", markdownEscape(baseline))
+	} else {
+		section.WriteString("| Resource type | Mean (s) |\n|---|---:|\n")
+	}
+	for _, resourceType := range resourceTypes {
+		if baselineMeans == nil {
+			fmt.Fprintf(&section, "| `%s` | %.2f |\n", resourceType, means[resourceType])
+			continue
+		}
+		delta := ""
+		if old := baselineMeans[resourceType]; old > 0 {
+			delta = fmt.Sprintf("%+.1f%%", (means[resourceType]-old)/old*100)
+		}
+		fmt.Fprintf(&section, "| `%s` | %.2f | %.2f | %s |\n", resourceType, baselineMeans[resourceType], means[resourceType], delta)
+	}
+	section.WriteString("\n</details>\n\n")
+	return section.String()
+}
+
+// indent prefixes every line of s with prefix
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// markdownEscape escapes characters that would break a table cell or be rendered as markup
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "*", `\*`, "_", `\_`, "<", "&lt;", ">", "&gt;", "`", "\\`").Replace(s)
+}
+
+// markdownCode formats values as a comma separated list of inline code
+func markdownCode(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "`" + strings.ReplaceAll(v, "`", "'") + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// plural returns word, followed by "s" unless n is 1
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+// writeMarkdownReportToFile writes the Markdown report to the run directory
+func (b *Benchmark) writeMarkdownReportToFile(results *Results) error {
+	reportPath := filepath.Join(b.runDir, markdownReportFileName)
+	b.logMessage(LogLevelInfo, "Writing Markdown report to %s", reportPath)
+	return writeResults(markdownReport{baseline: b.baselineReference(), maxBytes: b.MarkdownReportMaxBytes}, reportPath, results)
+}
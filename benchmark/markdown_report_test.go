@@ -0,0 +1,68 @@
+package benchmark
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func markdownTestResults() []PlanDetails {
+	return []PlanDetails{
+		{Version: "main", Command: []string{"terraform", "plan"}, Samples: samplesOf(10, 10.1, 9.9, 10, 10.2)},
+		{Version: "faster", Samples: samplesOf(8, 8.1, 7.9, 8, 8.2)},
+		{Version: "noisy", Samples: samplesOf(10)},
+		{
+			Version:         "feature/x",
+			Samples:         []Sample{{Iteration: 1, Phases: []Phase{{Name: phaseCommand, Duration: 10}}, Resources: map[string]float64{"genesyscloud_user": 2}}},
+			PlanDifferences: []string{"genesyscloud_user.alice: action differs"},
+		},
+	}
+}
+
+func TestMarkdownReport_Write(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (markdownReport{baseline: "main"}).Write(&buf, &Results{Results: markdownTestResults()}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, expected := range []string{
+		"## Terraform provider benchmark: Plan",
+		"Compared with baseline `main`: 0 regressions, 1 improvement.",
+		"| | main (baseline) | 10.00 ± 0.11 | | |",
+		"| 🟢 | faster | 8.00 ± 0.11 | -20.0% | 0.012 * |",
+		"| ⚪ | noisy | 10.00 ± 0.00 | +0.0% | 1.000 |",
+		"at least 4 iterations",
+		"<summary>⚠️ Failures (1)</summary>",
+		"- **feature/x**: plan differs from the baseline: `genesyscloud_user.alice: action differs`",
+		"<summary>Resource types: feature/x</summary>",
+		"| `genesyscloud_user` | 0.00 | 2.00 |  |",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestMarkdownReport_WriteTruncates(t *testing.T) {
+	data := markdownTestResults()
+	for i := 0; i < 50; i++ {
+		data = append(data, PlanDetails{Version: strings.Repeat("x", 100), Samples: samplesOf(1)})
+	}
+
+	var buf bytes.Buffer
+	if err := (markdownReport{baseline: "main", maxBytes: 1500}).Write(&buf, &Results{Results: data}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	output := buf.String()
+	if buf.Len() > 1500 {
+		t.Errorf("report is %d bytes, want at most 1500", buf.Len())
+	}
+	if !strings.Contains(output, "_Report truncated:") || !strings.Contains(output, "2 sections omitted") {
+		t.Errorf("expected truncation note, got:\n%s", output)
+	}
+	if !strings.Contains(output, "| | main (baseline)") {
+		t.Errorf("expected the first rows to be kept, got:\n%s", output)
+	}
+}
@@ -0,0 +1,169 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+const matrixTableFileName = "matrix.txt"
+
+// matrixValue is the value of a single axis within a matrix cell
+type matrixValue struct {
+	axis  MatrixAxis
+	value string
+}
+
+// matrixCell is one combination of matrix axis values. The empty cell is used when no matrix is configured.
+type matrixCell []matrixValue
+
+// run identifies a single measured execution of the terraform command
+type run struct {
+	reference string
+	sha       string
+	cell      matrixCell
+	iteration int
+}
+
+// matrixCells returns every combination of the configured matrix axis values, in axis order
+func (b *Benchmark) matrixCells() []matrixCell {
+	cells := []matrixCell{nil}
+	for _, axis := range b.Matrix {
+		var expanded []matrixCell
+		for _, cell := range cells {
+			for _, value := range axis.Values {
+				next := make(matrixCell, len(cell), len(cell)+1)
+				copy(next, cell)
+				expanded = append(expanded, append(next, matrixValue{axis: axis, value: value}))
+			}
+		}
+		cells = expanded
+	}
+	return cells
+}
+
+// validateMatrix checks that every axis is named uniquely, targets exactly one of a flag or environment variable and has values
+func (b *Benchmark) validateMatrix() error {
+	names := make(map[string]bool)
+	for _, axis := range b.Matrix {
+		if axis.Name == "" {
+			return errors.New("matrix axis name is required")
+		}
+		if names[axis.Name] {
+			return fmt.Errorf("matrix axis %s is defined more than once", axis.Name)
+		}
+		names[axis.Name] = true
+
+		if (axis.Flag == "") == (axis.EnvVar == "") {
+			return fmt.Errorf("matrix axis %s must set exactly one of Flag or EnvVar", axis.Name)
+		}
+		if len(axis.Values) == 0 {
+			return fmt.Errorf("matrix axis %s requires at least one value", axis.Name)
+		}
+	}
+	return nil
+}
+
+// args returns the flags the cell adds to the measured command
+func (c matrixCell) args() []string {
+	var args []string
+	for _, v := range c {
+		if v.axis.Flag != "" {
+			args = append(args, v.axis.Flag+"="+v.value)
+		}
+	}
+	return args
+}
+
+// env returns the environment variables the cell sets for the measured command
+func (c matrixCell) env() []string {
+	var env []string
+	for _, v := range c {
+		if v.axis.EnvVar != "" {
+			env = append(env, v.axis.EnvVar+"="+v.value)
+		}
+	}
+	return env
+}
+
+// values returns the cell's values keyed by axis name, or nil for the empty cell
+func (c matrixCell) values() map[string]string {
+	if len(c) == 0 {
+		return nil
+	}
+	values := make(map[string]string, len(c))
+	for _, v := range c {
+		values[v.axis.Name] = v.value
+	}
+	return values
+}
+
+// label returns a human readable description of the cell, e.g. "parallelism=10,retries=3"
+func (c matrixCell) label() string {
+	parts := make([]string, len(c))
+	for i, v := range c {
+		parts[i] = v.axis.Name + "=" + v.value
+	}
+	return strings.Join(parts, ",")
+}
+
+// logName returns the base name of the log file for the run, made safe for the file system
+func (r run) logName() string {
+	name := safeFileName(r.reference)
+	for _, v := range r.cell {
+		name += "_" + safeFileName(v.axis.Name) + "-" + safeFileName(v.value)
+	}
+	return name
+}
+
+// String returns the reference followed by the matrix cell, if any
+func (r run) String() string {
+	if len(r.cell) == 0 {
+		return r.reference
+	}
+	return fmt.Sprintf("%s [%s]", r.reference, r.cell.label())
+}
+
+// writeMatrixTable writes a table of durations keyed by reference, configuration and matrix cell
+func (b *Benchmark) writeMatrixTable(w io.Writer, data []PlanDetails) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	header := []string{"REFERENCE"}
+	if len(b.TfConfigs) > 0 {
+		header = append(header, "CONFIG")
+	}
+	for _, axis := range b.Matrix {
+		header = append(header, strings.ToUpper(axis.Name))
+	}
+	header = append(header, "DURATION (s)")
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, plan := range data {
+		row := []string{plan.Version}
+		if len(b.TfConfigs) > 0 {
+			row = append(row, plan.Config)
+		}
+		for _, axis := range b.Matrix {
+			row = append(row, plan.Cell[axis.Name])
+		}
+		row = append(row, fmt.Sprintf("%.2f", plan.Duration))
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+// writeMatrixTableToFile writes the results table to the performance directory and logs it
+func (b *Benchmark) writeMatrixTableToFile(data []PlanDetails) error {
+	var table strings.Builder
+	if err := b.writeMatrixTable(&table, data); err != nil {
+		return fmt.Errorf("failed to build matrix table: %w", err)
+	}
+
+	b.logMessage(LogLevelInfo, "Matrix results:\n%s", table.String())
+	return os.WriteFile(filepath.Join(b.performanceDir, matrixTableFileName), []byte(table.String()), 0644)
+}
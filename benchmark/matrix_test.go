@@ -0,0 +1,158 @@
+package benchmark
+
+import (
+	"strings"
+	"testing"
+)
+
+func testMatrix() []MatrixAxis {
+	return []MatrixAxis{
+		{Name: "parallelism", Flag: "-parallelism", Values: []string{"1", "10", "50"}},
+		{Name: "retries", EnvVar: "GENESYSCLOUD_SDK_MAX_RETRIES", Values: []string{"0", "3"}},
+	}
+}
+
+func TestBenchmark_matrixCells(t *testing.T) {
+	b := &Benchmark{}
+	cells := b.matrixCells()
+	if len(cells) != 1 || len(cells[0]) != 0 {
+		t.Fatalf("matrixCells() without a matrix = %v, want a single empty cell", cells)
+	}
+
+	b.Matrix = testMatrix()
+	cells = b.matrixCells()
+	if len(cells) != 6 {
+		t.Fatalf("matrixCells() returned %d cells, want 6", len(cells))
+	}
+
+	expected := []string{
+		"parallelism=1,retries=0",
+		"parallelism=1,retries=3",
+		"parallelism=10,retries=0",
+		"parallelism=10,retries=3",
+		"parallelism=50,retries=0",
+		"parallelism=50,retries=3",
+	}
+	for i, cell := range cells {
+		if cell.label() != expected[i] {
+			t.Errorf("cell %d label = %v, want %v", i, cell.label(), expected[i])
+		}
+	}
+}
+
+func TestMatrixCell_argsAndEnv(t *testing.T) {
+	b := &Benchmark{Matrix: testMatrix()}
+	cell := b.matrixCells()[3]
+
+	if args := cell.args(); strings.Join(args, " ") != "-parallelism=10" {
+		t.Errorf("args() = %v, want [-parallelism=10]", args)
+	}
+	if env := cell.env(); strings.Join(env, " ") != "GENESYSCLOUD_SDK_MAX_RETRIES=3" {
+		t.Errorf("env() = %v, want [GENESYSCLOUD_SDK_MAX_RETRIES=3]", env)
+	}
+
+	values := cell.values()
+	if values["parallelism"] != "10" || values["retries"] != "3" {
+		t.Errorf("values() = %v", values)
+	}
+	if (matrixCell{}).values() != nil {
+		t.Error("values() of the empty cell should be nil")
+	}
+}
+
+func TestBenchmark_validateMatrix(t *testing.T) {
+	tests := []struct {
+		name   string
+		matrix []MatrixAxis
+		errMsg string
+	}{
+		{name: "valid", matrix: testMatrix()},
+		{name: "missing name", matrix: []MatrixAxis{{Flag: "-parallelism", Values: []string{"1"}}}, errMsg: "name is required"},
+		{
+			name: "duplicate name",
+			matrix: []MatrixAxis{
+				{Name: "p", Flag: "-parallelism", Values: []string{"1"}},
+				{Name: "p", Flag: "-parallelism", Values: []string{"2"}},
+			},
+			errMsg: "defined more than once",
+		},
+		{name: "flag and env var", matrix: []MatrixAxis{{Name: "p", Flag: "-parallelism", EnvVar: "P", Values: []string{"1"}}}, errMsg: "exactly one of"},
+		{name: "neither flag nor env var", matrix: []MatrixAxis{{Name: "p", Values: []string{"1"}}}, errMsg: "exactly one of"},
+		{name: "no values", matrix: []MatrixAxis{{Name: "p", Flag: "-parallelism"}}, errMsg: "at least one value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Benchmark{Matrix: tt.matrix}).validateMatrix()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("validateMatrix() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateMatrix() error = %v, want %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestRun_logName(t *testing.T) {
+	b := &Benchmark{Matrix: testMatrix()}
+
+	if name := (run{reference: "main"}).logName(); name != "main" {
+		t.Errorf("logName() = %v, want main", name)
+	}
+	if name := (run{reference: "main", cell: b.matrixCells()[5]}).logName(); name != "main_parallelism-50_retries-3" {
+		t.Errorf("logName() = %v, want main_parallelism-50_retries-3", name)
+	}
+}
+
+func TestBenchmark_measuredCommandWithMatrix(t *testing.T) {
+	b := &Benchmark{TfCommand: Apply, Args: []string{"-refresh=false"}, Matrix: testMatrix()}
+	r := run{reference: "main", cell: b.matrixCells()[2]}
+
+	expected := "terraform apply --auto-approve -parallelism=10 -refresh=false"
+	if command := strings.Join(b.measuredCommand(&workspace{}, r), " "); command != expected {
+		t.Errorf("measuredCommand() = %v, want %v", command, expected)
+	}
+}
+
+func TestBenchmark_measuredCommandWithMatrixAndPositionalArgs(t *testing.T) {
+	b := &Benchmark{
+		TfCommand: Import,
+		Args:      []string{"genesyscloud_user.test", "0a1b2c3d"},
+		Matrix:    []MatrixAxis{{Name: "parallelism", Flag: "-parallelism", Values: []string{"10"}}},
+	}
+	r := run{reference: "main", cell: b.matrixCells()[0]}
+
+	// Flags must come before the address and ID or terraform import rejects them
+	expected := "terraform import -parallelism=10 genesyscloud_user.test 0a1b2c3d"
+	if command := strings.Join(b.measuredCommand(&workspace{}, r), " "); command != expected {
+		t.Errorf("measuredCommand() = %v, want %v", command, expected)
+	}
+}
+
+func TestBenchmark_writeMatrixTable(t *testing.T) {
+	b := &Benchmark{Matrix: testMatrix()}
+	data := []PlanDetails{
+		{Version: "main", Duration: 12.345, Cell: map[string]string{"parallelism": "1", "retries": "0"}},
+		{Version: "v1.66.0", Duration: 9.5, Cell: map[string]string{"parallelism": "50", "retries": "3"}},
+	}
+
+	var out strings.Builder
+	if err := b.writeMatrixTable(&out, data); err != nil {
+		t.Fatalf("writeMatrixTable() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d:\n%s", len(lines), out.String())
+	}
+	if fields := strings.Fields(lines[0]); strings.Join(fields, " ") != "REFERENCE PARALLELISM RETRIES DURATION (s)" {
+		t.Errorf("header = %v", lines[0])
+	}
+	if fields := strings.Fields(lines[2]); strings.Join(fields, " ") != "v1.66.0 50 3 9.50" {
+		t.Errorf("row = %v", lines[2])
+	}
+}
@@ -0,0 +1,157 @@
+package benchmark
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// openMetricsBuckets are the upper bounds in seconds of the command duration histogram
+var openMetricsBuckets = []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600, 1200}
+
+// OpenMetricsWriter writes the results in the OpenMetrics text format, which the node exporter textfile collector
+// can read. The file is replaced atomically so the collector never reads a partial file.
+type OpenMetricsWriter struct {
+	// Path is the absolute path to write to, e.g. "/var/lib/node_exporter/textfile/terraform_benchmark.prom"
+	// (Defaults to metrics.prom in the performance directory). Relative paths are rejected, as they would be written
+	// to the run directory where the collector never finds them.
+	Path string
+}
+
+// validate checks that Path, when set, is absolute
+func (o OpenMetricsWriter) validate() error {
+	if o.Path != "" && !filepath.IsAbs(o.Path) {
+		return fmt.Errorf("OpenMetrics path %s must be absolute", o.Path)
+	}
+	return nil
+}
+
+// openMetricsFamily is a metric name and its samples
+type openMetricsFamily struct {
+	name    string
+	help    string
+	kind    string
+	unit    string
+	samples []string
+}
+
+// FileName returns the path of the metrics file
+func (o OpenMetricsWriter) FileName() string {
+	if o.Path != "" {
+		return o.Path
+	}
+	return "metrics.prom"
+}
+
+// Write writes gauges of the mean duration, CPU time, peak memory and API calls of each phase, a histogram of the measured
+// command's duration across iterations and a gauge of failed results, labelled by reference, SHA, scenario and phase
+func (OpenMetricsWriter) Write(w io.Writer, results *Results) error {
+	data := results.Results
+	duration := &openMetricsFamily{name: "terraform_benchmark_phase_duration_seconds", kind: "gauge", unit: "seconds",
+		help: "Mean wall clock duration of a benchmark phase."}
+	userCPU := &openMetricsFamily{name: "terraform_benchmark_phase_user_cpu_seconds", kind: "gauge", unit: "seconds",
+		help: "Mean user CPU time of the processes run during a benchmark phase."}
+	systemCPU := &openMetricsFamily{name: "terraform_benchmark_phase_system_cpu_seconds", kind: "gauge", unit: "seconds",
+		help: "Mean system CPU time of the processes run during a benchmark phase."}
+	peakRSS := &openMetricsFamily{name: "terraform_benchmark_phase_peak_rss_bytes", kind: "gauge", unit: "bytes",
+		help: "Peak resident set size of the processes run during a benchmark phase."}
+	apiCalls := &openMetricsFamily{name: "terraform_benchmark_phase_api_calls", kind: "gauge",
+		help: "Mean number of HTTP requests the provider logged during a benchmark phase."}
+	command := &openMetricsFamily{name: "terraform_benchmark_command_duration_seconds", kind: "histogram", unit: "seconds",
+		help: "Duration of each iteration of the measured terraform command."}
+	failed := &openMetricsFamily{name: "terraform_benchmark_failed", kind: "gauge",
+		help: "1 if the benchmark of a reference failed, otherwise 0."}
+
+	for _, plan := range data {
+		labels := openMetricsLabels(plan)
+
+		// Only the failure of a failed result is reported, as its timings are incomplete
+		if plan.Failure != nil {
+			failed.add("", labels, 1)
+			continue
+		}
+
+		for _, phase := range meanPhases(plan) {
+			phaseLabels := labels + `,phase="` + openMetricsEscape(phase.Name) + `"`
+			duration.add("", phaseLabels, phase.Duration)
+			if phase.UserCPU > 0 || phase.SystemCPU > 0 {
+				userCPU.add("", phaseLabels, phase.UserCPU)
+				systemCPU.add("", phaseLabels, phase.SystemCPU)
+			}
+			if phase.PeakRSSBytes > 0 {
+				peakRSS.add("", phaseLabels, float64(phase.PeakRSSBytes))
+			}
+			if phase.APICalls > 0 {
+				apiCalls.add("", phaseLabels, float64(phase.APICalls))
+			}
+		}
+
+		durations := commandDurations(plan)
+		if len(plan.Samples) > 0 && len(durations) > 0 {
+			var sum float64
+			for _, bound := range openMetricsBuckets {
+				count := 0
+				for _, d := range durations {
+					if d <= bound {
+						count++
+					}
+				}
+				command.add("_bucket", labels+`,le="`+formatOpenMetricsValue(bound)+`"`, float64(count))
+			}
+			for _, d := range durations {
+				sum += d
+			}
+			command.add("_bucket", labels+`,le="+Inf"`, float64(len(durations)))
+			command.add("_count", labels, float64(len(durations)))
+			command.add("_sum", labels, sum)
+		}
+
+		failed.add("", labels, 0)
+	}
+
+	for _, family := range []*openMetricsFamily{duration, userCPU, systemCPU, peakRSS, apiCalls, command, failed} {
+		if err := family.write(w); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+// add adds a sample to the family
+func (f *openMetricsFamily) add(suffix, labels string, value float64) {
+	f.samples = append(f.samples, fmt.Sprintf("%s%s{%s} %s", f.name, suffix, labels, formatOpenMetricsValue(value)))
+}
+
+// write writes the metadata and samples of the family, or nothing if it has no samples
+func (f *openMetricsFamily) write(w io.Writer) error {
+	if len(f.samples) == 0 {
+		return nil
+	}
+
+	header := fmt.Sprintf("# TYPE %s %s\n", f.name, f.kind)
+	if f.unit != "" {
+		header += fmt.Sprintf("# UNIT %s %s\n", f.name, f.unit)
+	}
+	header += fmt.Sprintf("# HELP %s %s\n", f.name, f.help)
+	_, err := io.WriteString(w, header+strings.Join(f.samples, "\n")+"\n")
+	return err
+}
+
+// openMetricsLabels returns the labels identifying a result
+func openMetricsLabels(plan PlanDetails) string {
+	return fmt.Sprintf(`command="%s",ref="%s",sha="%s",scenario="%s"`,
+		openMetricsEscape(commandName(plan.Command)), openMetricsEscape(plan.Version), openMetricsEscape(plan.SHA), openMetricsEscape(scenarioName(plan)))
+}
+
+// openMetricsEscape escapes a label value
+func openMetricsEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatOpenMetricsValue formats a sample value or bucket bound
+func formatOpenMetricsValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,77 @@
+package benchmark
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenMetricsWriter_Write(t *testing.T) {
+	data := testResults()
+	data[0].Command = []string{"terraform", "plan"}
+	data = append(data, PlanDetails{Version: `feature/"quoted"`, Command: []string{"terraform", "plan"}, Setup: []Phase{{Name: phaseBuild, Duration: 3}}, Failure: &Failure{Phase: phaseBuild}})
+
+	var buf bytes.Buffer
+	if err := (OpenMetricsWriter{}).Write(&buf, &Results{Results: data}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	output := buf.String()
+	labels := `command="Plan",ref="main",sha="abc1234",scenario="small-org [log=DEBUG,parallelism=10]"`
+	for _, expected := range []string{
+		"# TYPE terraform_benchmark_phase_duration_seconds gauge\n# UNIT terraform_benchmark_phase_duration_seconds seconds\n",
+		`terraform_benchmark_phase_duration_seconds{` + labels + `,phase="checkout"} 1` + "\n",
+		`terraform_benchmark_phase_duration_seconds{` + labels + `,phase="command"} 2.25` + "\n",
+		`terraform_benchmark_phase_user_cpu_seconds{` + labels + `,phase="command"} 0.625` + "\n",
+		`terraform_benchmark_phase_peak_rss_bytes{` + labels + `,phase="command"} 1024` + "\n",
+		"# TYPE terraform_benchmark_phase_api_calls gauge\n",
+		`terraform_benchmark_phase_api_calls{` + labels + `,phase="command"} 21` + "\n",
+		"# TYPE terraform_benchmark_command_duration_seconds histogram\n",
+		`terraform_benchmark_command_duration_seconds_bucket{` + labels + `,le="2"} 1` + "\n",
+		`terraform_benchmark_command_duration_seconds_bucket{` + labels + `,le="5"} 2` + "\n",
+		`terraform_benchmark_command_duration_seconds_bucket{` + labels + `,le="+Inf"} 2` + "\n",
+		`terraform_benchmark_command_duration_seconds_count{` + labels + `} 2` + "\n",
+		`terraform_benchmark_command_duration_seconds_sum{` + labels + `} 4.5` + "\n",
+		`terraform_benchmark_failed{` + labels + `} 0` + "\n",
+		`terraform_benchmark_failed{command="Plan",ref="feature/\"quoted\"",sha="",scenario=""} 1` + "\n",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	// A failed result is only reported as failed, as its timings are incomplete
+	if strings.Contains(output, `ref="feature/\"quoted\"",sha="",scenario="",phase=`) {
+		t.Errorf("expected no phase metrics for the failed result, got:\n%s", output)
+	}
+
+	if !strings.HasSuffix(output, "# EOF\n") {
+		t.Error("expected output to end with # EOF")
+	}
+	if strings.Count(output, "# TYPE terraform_benchmark_failed gauge") != 1 {
+		t.Error("expected each metric family to be declared once")
+	}
+}
+
+func TestBenchmark_writeDataToFile_AbsolutePath(t *testing.T) {
+	textfileDir := t.TempDir()
+	path := filepath.Join(textfileDir, "terraform_benchmark.prom")
+	b := &Benchmark{
+		LogLevel:       LogLevelQuiet,
+		performanceDir: t.TempDir(),
+		ResultWriters:  []ResultWriter{OpenMetricsWriter{Path: path}},
+	}
+
+	if err := b.writeDataToFile(&Results{Results: testResults()}); err != nil {
+		t.Fatalf("writeDataToFile() error = %v", err)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected metrics to be written to %s: %v", path, err)
+	}
+	if entries, _ := os.ReadDir(textfileDir); len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+}
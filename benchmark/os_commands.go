@@ -1,97 +1,281 @@
 package benchmark
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 )
 
-func (b *Benchmark) initialiseTerraform() error {
-	command := []string{"terraform", "init"}
-	b.logMessage(LogLevelInfo, "Running %v in directory %s", command, b.TfConfigDir)
+// initialiseTerraform runs terraform init in the workspace, writing its output to outputPath
+func (b *Benchmark) initialiseTerraform(ws *workspace, outputPath string) error {
+	command := b.buildCommand(ws, Init, false)
+	b.logMessage(LogLevelInfo, "Running %v in directory %s", redactCommand(command), ws.dir)
 
-	outputFile, err := os.OpenFile(b.initLogFilePath, os.O_WRONLY|os.O_TRUNC, 0644)
+	outputFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return fmt.Errorf("failed to open output file: %v", err)
 	}
 	defer outputFile.Close()
 
-	cmd := b.setupTerraformCommand(command, outputFile, false)
+	cmd := b.setupTerraformCommand(ws.dir, command, outputFile, false, ws.env...)
 
-	if err := cmd.Run(); err != nil {
+	if err := b.runCommand(cmd); err != nil {
 		return fmt.Errorf("terraform init failed: %v", err)
 	}
 
+	ws.initialised = true
 	return nil
 }
 
-// runTerraformCommand executes terraform command and captures output
-func (b *Benchmark) runTerraformCommand(reference string) error {
-	outputFileName := b.generateLogFilePath(reference)
+// runTerraformCommand executes terraform command and captures output, returning the time spent on each resource type
+// when ResourceTimings is set
+func (b *Benchmark) runTerraformCommand(ws *workspace, r run) (map[string]float64, error) {
+	outputFileName := ws.commandLogFilePath(r)
 
 	b.logMessage(LogLevelDebug, "Opening output file %s", outputFileName)
-	outputFile, err := os.OpenFile(outputFileName, os.O_WRONLY|os.O_TRUNC, 0644)
+	outputFile, err := os.OpenFile(outputFileName, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		return fmt.Errorf("failed to open output file: %v", err)
+		return nil, fmt.Errorf("failed to open output file: %v", err)
 	}
 	defer outputFile.Close()
 
-	// Split the command into executable and arguments
-	commandParts := strings.Fields(string(b.TfCommand))
+	commandParts := b.measuredCommand(ws, r)
 	if len(commandParts) == 0 {
-		return fmt.Errorf("invalid command: %s", string(b.TfCommand))
+		return nil, fmt.Errorf("invalid command: %s", string(b.TfCommand))
 	}
 
-	cmd := b.setupTerraformCommand(commandParts, outputFile, true)
+	env := append(append([]string{}, ws.env...), r.cell.env()...)
+	cmd := b.setupTerraformCommand(ws.dir, commandParts, outputFile, true, env...)
+
+	var output bytes.Buffer
+	if b.ResourceTimings {
+		cmd.Stdout = io.MultiWriter(outputFile, &output)
+	}
 
-	b.logMessage(LogLevelInfo, "⌛️ Running %s for version %s in directory %s", string(b.TfCommand), reference, b.TfConfigDir)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("terraform command failed: %w", err)
+	// Terraform writes its log, including the provider's, to stderr
+	var apiCalls apiCallCounter
+	cmd.Stderr = io.MultiWriter(outputFile, &apiCalls)
+
+	b.logMessage(LogLevelInfo, "⌛️ Running %s for version %s in directory %s", string(b.TfCommand), r, ws.dir)
+	err = b.runCommand(cmd)
+	b.recordAPICalls(&apiCalls)
+	if err != nil {
+		return nil, fmt.Errorf("terraform command failed: %w", err)
 	}
 
 	b.logMessage(LogLevelInfo, "✅ Successfully completed command: %s", string(b.TfCommand))
-	return nil
+	if !b.ResourceTimings {
+		return nil, nil
+	}
+	return parseResourceTimings(&output)
 }
 
-// makeSideload checks out the specified ref and runs make sideload
-func (b *Benchmark) makeSideload(ref string) (err error) {
-	b.logMessage(LogLevelInfo, "Checking out reference %s in %s", ref, b.ProjectPath)
-	// Checkout specific hash
-	cmd := exec.Command("git", "checkout", ref)
-	cmd.Dir = b.ProjectPath
-	if err = cmd.Run(); err != nil {
-		return fmt.Errorf("git checkout failed: %w", err)
+// makeSideload checks out the specified ref and runs make sideload, returning the phases it ran, including any that
+// failed. The output of each is written to a log named after logName, e.g. main_build.log.
+func (b *Benchmark) makeSideload(ref, logName string) ([]Phase, error) {
+	checkout, err := b.runPhase(phaseCheckout, func() error {
+		b.logMessage(LogLevelInfo, "Checking out reference %s in %s", ref, b.ProjectPath)
+		// Checkout specific hash
+		cmd := exec.Command("git", "checkout", ref)
+		cmd.Dir = b.ProjectPath
+		if err := b.runLoggedCommand(cmd, b.stepLogFilePath(logName, phaseCheckout)); err != nil {
+			return fmt.Errorf("git checkout failed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return []Phase{checkout}, err
 	}
 
-	b.logMessage(LogLevelInfo, "Running make sideload in %s", b.ProjectPath)
-	// Run make sideload
-	cmd = exec.Command("make", "sideload")
+	build, err := b.runPhase(phaseBuild, func() error {
+		b.logMessage(LogLevelInfo, "Running make sideload in %s", b.ProjectPath)
+		// Run make sideload
+		cmd := exec.Command("make", "sideload")
+		cmd.Dir = b.ProjectPath
+		if err := b.runLoggedCommand(cmd, b.stepLogFilePath(logName, phaseBuild)); err != nil {
+			return fmt.Errorf("make sideload failed: %w", err)
+		}
+		return nil
+	})
+	return []Phase{checkout, build}, err
+}
+
+// referenceLogName returns the base name of the logs of a reference
+func referenceLogName(reference string) string {
+	return safeFileName(reference)
+}
+
+// seedLogName returns the base name of the logs written while seeding state with a reference. The seed reference is
+// usually benchmarked as well, so its seed logs are kept apart from those of its run. As safe names containing an
+// underscore always end in a hash, no reference's logs share the name.
+func seedLogName(reference string) string {
+	return "seed_" + safeFileName(reference)
+}
+
+// stepLogFilePath returns the log of a step run once for the logs named logName, e.g. main_checkout.log
+func (b *Benchmark) stepLogFilePath(logName, step string) string {
+	return filepath.Join(b.logsDir, logFileName(logName+"_"+step))
+}
+
+// commandLogFilePath returns the log of the measured command in an iteration of a run, e.g. main_2.log
+func (ws *workspace) commandLogFilePath(r run) string {
+	return ws.logFilePath(logFileName(fmt.Sprintf("%s_%d", r.logName(), r.iteration)))
+}
+
+// iterationLogFilePath returns the log of a step run for an iteration of a run, e.g. main_destroy_2.log
+func (ws *workspace) iterationLogFilePath(r run, step string) string {
+	return ws.logFilePath(logFileName(fmt.Sprintf("%s_%s_%d", r.logName(), step, r.iteration)))
+}
+
+// resolveSHA returns the commit currently checked out in the project
+func (b *Benchmark) resolveSHA() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
 	cmd.Dir = b.ProjectPath
-	if err = cmd.Run(); err != nil {
-		return fmt.Errorf("make sideload failed: %w", err)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
 	}
+	return strings.TrimSpace(string(output)), nil
+}
 
-	return err
+// prepareState runs the pre-run command for the measured terraform command, if it has one, writing its output to
+// outputPath
+func (b *Benchmark) prepareState(ws *workspace, outputPath string) error {
+	switch b.TfCommand.spec().preRun {
+	case Destroy:
+		if err := b.destroy(ws, outputPath); err != nil {
+			return fmt.Errorf("destroy failed: %v", err)
+		}
+	case Apply:
+		if err := b.apply(ws, outputPath); err != nil {
+			return fmt.Errorf("apply failed: %v", err)
+		}
+	case stateRm:
+		if err := b.removeImportedResource(ws, outputPath); err != nil {
+			return fmt.Errorf("state rm failed: %v", err)
+		}
+	}
+	return nil
 }
 
-// destroy runs terraform destroy with optional confirmation
-func (b *Benchmark) destroy() error {
-	command := []string{"terraform", "destroy", "--auto-approve"}
-	b.logMessage(LogLevelInfo, "🔥 Running %v in directory %s", command, b.TfConfigDir)
+// prepareLogFilePath returns the log of the step preparing the state for an iteration of a run, or "" if there is none
+func (b *Benchmark) prepareLogFilePath(ws *workspace, r run) string {
+	if ws.snapshot != "" {
+		return ws.iterationLogFilePath(r, "restore")
+	}
+	switch b.TfCommand.spec().preRun {
+	case Destroy:
+		return ws.iterationLogFilePath(r, "destroy")
+	case Apply:
+		return ws.iterationLogFilePath(r, "apply")
+	case stateRm:
+		return ws.iterationLogFilePath(r, "state-rm")
+	}
+	return ""
+}
 
-	outputFile, err := os.OpenFile(b.destroyLogFilePath, os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open output file: %v", err)
+// destroy runs terraform destroy after checking what it would remove against the destroy guard, writing its output to
+// outputPath and the preview's alongside it
+func (b *Benchmark) destroy(ws *workspace, outputPath string) error {
+	if err := b.guardDestroy(ws, previewLogFilePath(outputPath)); err != nil {
+		return err
 	}
-	defer outputFile.Close()
 
-	cmd := b.setupTerraformCommand(command, outputFile, true)
+	command := b.buildCommand(ws, Destroy, false)
+	b.logMessage(LogLevelInfo, "🔥 Running %v in directory %s", redactCommand(command), ws.dir)
 
-	if err := cmd.Run(); err != nil {
+	if err := b.runSetupCommand(ws, command, outputPath); err != nil {
 		return fmt.Errorf("destroy failed: %v", err)
 	}
 
 	b.logMessage(LogLevelInfo, "🔥 Destroy successful")
 	return nil
 }
+
+// apply runs terraform apply so that commands which tear down infrastructure have something to measure, writing its
+// output to outputPath
+func (b *Benchmark) apply(ws *workspace, outputPath string) error {
+	command := b.buildCommand(ws, Apply, false)
+	b.logMessage(LogLevelInfo, "🏗️ Running %v in directory %s", redactCommand(command), ws.dir)
+
+	if err := b.runSetupCommand(ws, command, outputPath); err != nil {
+		return fmt.Errorf("apply failed: %v", err)
+	}
+
+	b.logMessage(LogLevelInfo, "🏗️ Apply successful")
+	return nil
+}
+
+// noStateFileMessage is the error terraform state commands report when there is no state yet
+const noStateFileMessage = "No state file was found"
+
+// importAddress returns the address of the resource imported by Import, which is the second to last of Args
+func (b *Benchmark) importAddress() string {
+	if len(b.Args) < 2 {
+		return ""
+	}
+	return b.Args[len(b.Args)-2]
+}
+
+// removeImportedResource removes the resource the measured import adds from the workspace's state, if it is there, so
+// that every run imports it afresh. The resource itself is left untouched. Output is written to outputPath.
+func (b *Benchmark) removeImportedResource(ws *workspace, outputPath string) error {
+	outputFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer outputFile.Close()
+
+	address := b.importAddress()
+	var stdout, stderr bytes.Buffer
+	list := b.setupTerraformCommand(ws.dir, []string{"terraform", "state", "list", address}, outputFile, true, ws.env...)
+	list.Stdout = io.MultiWriter(outputFile, &stdout)
+	list.Stderr = io.MultiWriter(outputFile, &stderr)
+	if err := b.runCommand(list); err != nil {
+		// Before the first import there may be no state at all
+		if strings.Contains(stderr.String(), noStateFileMessage) {
+			return nil
+		}
+		return fmt.Errorf("terraform state list failed: %v", err)
+	}
+	if !slices.Contains(parseStateList(stdout.String()), address) {
+		b.logMessage(LogLevelDebug, "%s is not in the state of %s", address, ws)
+		return nil
+	}
+
+	command := append(strings.Fields(string(stateRm)), address)
+	b.logMessage(LogLevelInfo, "🧽 Running %v in directory %s", redactCommand(command), ws.dir)
+	return b.runCommand(b.setupTerraformCommand(ws.dir, command, outputFile, true, ws.env...))
+}
+
+// runSetupCommand runs an unmeasured terraform command against the sideloaded provider, writing its output to outputPath
+// and adding any extra environment variables given
+func (b *Benchmark) runSetupCommand(ws *workspace, command []string, outputPath string, extraEnv ...string) error {
+	outputFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer outputFile.Close()
+
+	env := append(append([]string{}, ws.env...), extraEnv...)
+	cmd := b.setupTerraformCommand(ws.dir, command, outputFile, true, env...)
+	return b.runCommand(cmd)
+}
+
+// runLoggedCommand runs cmd, writing its output to outputPath
+func (b *Benchmark) runLoggedCommand(cmd *exec.Cmd, outputPath string) error {
+	outputFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer outputFile.Close()
+
+	cmd.Stdout = outputFile
+	cmd.Stderr = outputFile
+	return b.runCommand(cmd)
+}
@@ -0,0 +1,64 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+// validateReferenceOverrides checks that every override has a valid pattern, existing directories and refers to a
+// configuration that is being benchmarked
+func (b *Benchmark) validateReferenceOverrides() error {
+	configs := make(map[string]bool)
+	for _, config := range b.tfConfigs() {
+		configs[config.Name] = true
+	}
+
+	for _, override := range b.ReferenceOverrides {
+		if override.Pattern == "" {
+			return errors.New("reference override pattern is required")
+		}
+		if _, err := path.Match(override.Pattern, ""); err != nil {
+			return fmt.Errorf("invalid reference override pattern %s: %w", override.Pattern, err)
+		}
+		if override.ConfigDir == "" && override.OverlayDir == "" {
+			return fmt.Errorf("reference override %s must set ConfigDir or OverlayDir", override.Pattern)
+		}
+		if override.Config != "" && !configs[override.Config] {
+			return fmt.Errorf("reference override %s refers to unknown terraform config %s", override.Pattern, override.Config)
+		}
+		for _, dir := range []string{override.ConfigDir, override.OverlayDir} {
+			if dir == "" {
+				continue
+			}
+			if _, err := os.Stat(dir); os.IsNotExist(err) {
+				return fmt.Errorf("reference override directory does not exist at %s", dir)
+			}
+		}
+
+		// The override's configuration replaces the original, so it needs var-files of its own
+		if override.ConfigDir != "" {
+			if err := b.validateVarFiles(override.ConfigDir, override.OverlayDir); err != nil {
+				return fmt.Errorf("reference override %s: %w", override.Pattern, err)
+			}
+			if err := validateModuleSources(override.ConfigDir); err != nil {
+				return fmt.Errorf("reference override %s: %w", override.Pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// referenceOverride returns the first override matching the reference for a configuration, or nil if there is none
+func (b *Benchmark) referenceOverride(config TfConfig, reference string) *ReferenceOverride {
+	for i, override := range b.ReferenceOverrides {
+		if override.Config != "" && override.Config != config.Name {
+			continue
+		}
+		if matched, _ := path.Match(override.Pattern, reference); matched {
+			return &b.ReferenceOverrides[i]
+		}
+	}
+	return nil
+}
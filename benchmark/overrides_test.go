@@ -0,0 +1,148 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchmark_validateReferenceOverrides(t *testing.T) {
+	tempDir := t.TempDir()
+
+	tests := []struct {
+		name     string
+		override ReferenceOverride
+		errMsg   string
+	}{
+		{name: "valid overlay", override: ReferenceOverride{Pattern: "v1.4*", OverlayDir: tempDir}},
+		{name: "valid config dir", override: ReferenceOverride{Pattern: "v1.40.0", Config: "small", ConfigDir: tempDir}},
+		{name: "missing pattern", override: ReferenceOverride{OverlayDir: tempDir}, errMsg: "pattern is required"},
+		{name: "bad pattern", override: ReferenceOverride{Pattern: "v1.[", OverlayDir: tempDir}, errMsg: "invalid reference override pattern"},
+		{name: "nothing to apply", override: ReferenceOverride{Pattern: "v1.4*"}, errMsg: "must set ConfigDir or OverlayDir"},
+		{name: "unknown config", override: ReferenceOverride{Pattern: "v1.4*", Config: "large", OverlayDir: tempDir}, errMsg: "unknown terraform config large"},
+		{name: "missing directory", override: ReferenceOverride{Pattern: "v1.4*", OverlayDir: filepath.Join(tempDir, "missing")}, errMsg: "does not exist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Benchmark{
+				TfConfigs:          []TfConfig{{Name: "small", Dir: tempDir}},
+				ReferenceOverrides: []ReferenceOverride{tt.override},
+			}
+			err := b.validateReferenceOverrides()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("validateReferenceOverrides() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateReferenceOverrides() error = %v, want %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestBenchmark_validateReferenceOverrides_varFiles(t *testing.T) {
+	configDir := t.TempDir()
+	overrideDir := t.TempDir()
+	overlayDir := t.TempDir()
+	for _, dir := range []string{configDir, overlayDir} {
+		if err := os.WriteFile(filepath.Join(dir, "vars.tfvars"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b := &Benchmark{
+		TfConfigDir:        configDir,
+		VarFiles:           []string{"vars.tfvars"},
+		ReferenceOverrides: []ReferenceOverride{{Pattern: "v1.4*", ConfigDir: overrideDir}},
+	}
+	err := b.validateReferenceOverrides()
+	if expected := "var file does not exist at " + filepath.Join(overrideDir, "vars.tfvars"); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("validateReferenceOverrides() error = %v, want %v", err, expected)
+	}
+
+	// The overlay is copied over the override's configuration, so its var-files are found too
+	b.ReferenceOverrides[0].OverlayDir = overlayDir
+	if err := b.validateReferenceOverrides(); err != nil {
+		t.Errorf("validateReferenceOverrides() error = %v", err)
+	}
+}
+
+func TestBenchmark_referenceOverride(t *testing.T) {
+	b := &Benchmark{
+		ReferenceOverrides: []ReferenceOverride{
+			{Pattern: "v1.40.*", Config: "large", ConfigDir: "/configs/large-v1.40"},
+			{Pattern: "v1.4*", OverlayDir: "/overlays/v1.4x"},
+		},
+	}
+
+	tests := []struct {
+		config    string
+		reference string
+		expected  string
+	}{
+		{"large", "v1.40.2", "/configs/large-v1.40"},
+		{"small", "v1.40.2", "/overlays/v1.4x"},
+		{"small", "v1.45.0", "/overlays/v1.4x"},
+		{"small", "main", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.config+"/"+tt.reference, func(t *testing.T) {
+			override := b.referenceOverride(TfConfig{Name: tt.config}, tt.reference)
+			result := ""
+			if override != nil {
+				result = override.ConfigDir + override.OverlayDir
+			}
+			if result != tt.expected {
+				t.Errorf("referenceOverride() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	files := map[string]string{
+		"main.tf":                      "resource",
+		"modules/users/main.tf":        "module",
+		"terraform.tfstate":            "state",
+		".terraform/providers/plugin":  "binary",
+		".terraform.lock.hcl":          "lock",
+		"modules/users/.terraform/foo": "nested working data is copied",
+	}
+	for name, content := range files {
+		path := filepath.Join(src, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	if err := copyDir(src, dst, terraformStateFiles); err != nil {
+		t.Fatalf("copyDir() error = %v", err)
+	}
+
+	for _, name := range []string{"main.tf", "modules/users/main.tf", ".terraform.lock.hcl", "modules/users/.terraform/foo"} {
+		content, err := os.ReadFile(filepath.Join(dst, name))
+		if err != nil {
+			t.Errorf("%s was not copied: %v", name, err)
+			continue
+		}
+		if string(content) != files[name] {
+			t.Errorf("%s = %q, want %q", name, content, files[name])
+		}
+	}
+	for _, name := range []string{"terraform.tfstate", ".terraform"} {
+		if _, err := os.Stat(filepath.Join(dst, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should not have been copied", name)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package benchmark
+
+import (
+	"os"
+	"os/exec"
+	"time"
+)
+
+// phaseNames are the names of the steps recorded as phases
+const (
+	phaseCheckout    = "checkout"
+	phaseBuild       = "build"
+	phaseInit        = "init"
+	phasePrepare     = "prepare"
+	phaseCommand     = "command"
+	phaseShow        = "show"
+	phaseIdempotency = "idempotency"
+)
+
+// phaseError is returned by runPhase when a phase fails, so the failure can be attributed to the phase
+type phaseError struct {
+	phase string
+	err   error
+}
+
+func (e *phaseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *phaseError) Unwrap() error {
+	return e.err
+}
+
+// runPhase times fn, accumulating the resource usage of every command it runs through runCommand
+func (b *Benchmark) runPhase(name string, fn func() error) (Phase, error) {
+	phase := &Phase{Name: name, Start: time.Now()}
+
+	b.currentPhase = phase
+	err := fn()
+	b.currentPhase = nil
+
+	phase.End = time.Now()
+	phase.Duration = phase.End.Sub(phase.Start).Seconds()
+	if err != nil {
+		return *phase, &phaseError{phase: name, err: err}
+	}
+	return *phase, nil
+}
+
+// runCommand runs cmd and adds its resource usage to the current phase
+func (b *Benchmark) runCommand(cmd *exec.Cmd) error {
+	err := cmd.Run()
+	b.recordUsage(cmd.ProcessState)
+	return err
+}
+
+// recordUsage adds the CPU time and peak memory of an exited process to the current phase
+func (b *Benchmark) recordUsage(state *os.ProcessState) {
+	if b.currentPhase == nil || state == nil {
+		return
+	}
+
+	b.currentPhase.UserCPU += state.UserTime().Seconds()
+	b.currentPhase.SystemCPU += state.SystemTime().Seconds()
+	if rss := peakRSSBytes(state); rss > b.currentPhase.PeakRSSBytes {
+		b.currentPhase.PeakRSSBytes = rss
+	}
+}
+
+// iterations returns the number of times each command is measured
+func (b *Benchmark) iterations() int {
+	if b.Iterations < 1 {
+		return 1
+	}
+	return b.Iterations
+}
@@ -0,0 +1,182 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// resourceChange is a resource change from the JSON representation of a saved plan
+type resourceChange struct {
+	Address string `json:"address"`
+	Type    string `json:"type"`
+	Change  struct {
+		Actions []string `json:"actions"`
+		After   any      `json:"after"`
+	} `json:"change"`
+}
+
+// planRepresentation is the part of terraform show -json output for a saved plan that is compared across references
+type planRepresentation struct {
+	ResourceChanges []resourceChange `json:"resource_changes"`
+}
+
+// planFilePath returns the absolute path the plan of a run is saved to
+func (b *Benchmark) planFilePath(ws *workspace, r run) string {
+	path := filepath.Join(b.plansDir, ws.config.Name, strings.TrimSuffix(logFileName(r.logName()), ".log")+".tfplan")
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// showPlan converts the saved plan of a run to JSON, keeps a copy alongside the plan and returns its resource changes
+// keyed by address
+func (b *Benchmark) showPlan(ws *workspace, r run) (map[string]resourceChange, error) {
+	planFilePath := b.planFilePath(ws, r)
+	outputPath := ws.logFilePath(logFileName(r.logName() + "_show"))
+
+	logFile, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer logFile.Close()
+
+	var stdout bytes.Buffer
+	cmd := b.setupTerraformCommand(ws.dir, []string{"terraform", "show", "-json", planFilePath}, logFile, true, ws.env...)
+	cmd.Stdout = &stdout
+	if err := b.runCommand(cmd); err != nil {
+		return nil, fmt.Errorf("terraform show failed: %v", err)
+	}
+
+	if err := os.WriteFile(strings.TrimSuffix(planFilePath, ".tfplan")+".json", stdout.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write plan JSON: %w", err)
+	}
+
+	return parsePlanRepresentation(stdout.Bytes())
+}
+
+// parsePlanRepresentation returns the resource changes of a JSON plan keyed by address
+func parsePlanRepresentation(data []byte) (map[string]resourceChange, error) {
+	var plan planRepresentation
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan JSON: %w", err)
+	}
+
+	changes := make(map[string]resourceChange, len(plan.ResourceChanges))
+	for _, change := range plan.ResourceChanges {
+		changes[change.Address] = change
+	}
+	return changes, nil
+}
+
+// comparePlans describes every difference in planned actions or values between a plan and the baseline plan
+func comparePlans(baseline, plan map[string]resourceChange) []string {
+	addresses := make(map[string]bool)
+	for address := range baseline {
+		addresses[address] = true
+	}
+	for address := range plan {
+		addresses[address] = true
+	}
+
+	sorted := make([]string, 0, len(addresses))
+	for address := range addresses {
+		sorted = append(sorted, address)
+	}
+	sort.Strings(sorted)
+
+	var differences []string
+	for _, address := range sorted {
+		expected, inBaseline := baseline[address]
+		actual, inPlan := plan[address]
+
+		switch {
+		case !inPlan:
+			differences = append(differences, fmt.Sprintf("%s: missing from plan", address))
+		case !inBaseline:
+			differences = append(differences, fmt.Sprintf("%s: not in baseline plan", address))
+		case !reflect.DeepEqual(expected.Change.Actions, actual.Change.Actions):
+			differences = append(differences, fmt.Sprintf("%s: actions %v differ from baseline %v", address, actual.Change.Actions, expected.Change.Actions))
+		case !reflect.DeepEqual(expected.Change.After, actual.Change.After):
+			differences = append(differences, fmt.Sprintf("%s: planned values differ from baseline (%s)", address, strings.Join(differingAttributes(expected.Change.After, actual.Change.After), ", ")))
+		}
+	}
+	return differences
+}
+
+// differingAttributes returns the top level attributes whose planned values differ
+func differingAttributes(expected, actual any) []string {
+	expectedAttributes, ok1 := expected.(map[string]any)
+	actualAttributes, ok2 := actual.(map[string]any)
+	if !ok1 || !ok2 {
+		return []string{"all values"}
+	}
+
+	var names []string
+	for name, value := range expectedAttributes {
+		if !reflect.DeepEqual(value, actualAttributes[name]) {
+			names = append(names, name)
+		}
+	}
+	for name := range actualAttributes {
+		if _, ok := expectedAttributes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resultKey identifies the configuration and matrix cell of a result, so results for different references can be
+// compared like for like
+func resultKey(plan PlanDetails) string {
+	names := make([]string, 0, len(plan.Cell))
+	for name := range plan.Cell {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	key := plan.Config
+	for _, name := range names {
+		key += "|" + name + "=" + plan.Cell[name]
+	}
+	return key
+}
+
+// checkPlanEquivalence compares each result's plan with the baseline reference's plan for the same configuration and
+// matrix cell, recording and logging any differences
+func (b *Benchmark) checkPlanEquivalence(data []PlanDetails) {
+	baselines := make(map[string]map[string]resourceChange)
+	for _, plan := range data {
+		if plan.Version == b.baselineReference() && plan.Failure == nil {
+			baselines[resultKey(plan)] = plan.resourceChanges
+		}
+	}
+
+	equivalent := true
+	for i, plan := range data {
+		baseline, ok := baselines[resultKey(plan)]
+		if !ok || plan.Version == b.baselineReference() || plan.Failure != nil {
+			continue
+		}
+
+		data[i].PlanDifferences = comparePlans(baseline, plan.resourceChanges)
+		if len(data[i].PlanDifferences) > 0 {
+			equivalent = false
+			b.logMessage(LogLevelInfo, "⚠️ Plan for reference %s differs from baseline %s:", plan.Version, b.baselineReference())
+			for _, difference := range data[i].PlanDifferences {
+				b.logMessage(LogLevelInfo, "⚠️   %s", difference)
+			}
+		}
+	}
+
+	if equivalent {
+		b.logMessage(LogLevelInfo, "🟰 Every plan is equivalent to the baseline %s", b.baselineReference())
+	}
+}
@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testBaselinePlanJSON = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "genesyscloud_user.alice", "type": "genesyscloud_user", "change": {"actions": ["create"], "after": {"name": "Alice", "email": "alice@example.com"}}},
+    {"address": "genesyscloud_user.bob", "type": "genesyscloud_user", "change": {"actions": ["no-op"], "after": {"name": "Bob"}}},
+    {"address": "genesyscloud_group.team", "type": "genesyscloud_group", "change": {"actions": ["no-op"], "after": {"name": "Team"}}}
+  ]
+}`
+
+const testChangedPlanJSON = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "genesyscloud_user.alice", "type": "genesyscloud_user", "change": {"actions": ["create"], "after": {"name": "Alice", "email": "ALICE@example.com"}}},
+    {"address": "genesyscloud_user.bob", "type": "genesyscloud_user", "change": {"actions": ["update"], "after": {"name": "Bob"}}},
+    {"address": "genesyscloud_user.carol", "type": "genesyscloud_user", "change": {"actions": ["create"], "after": {"name": "Carol"}}}
+  ]
+}`
+
+func TestComparePlans(t *testing.T) {
+	baseline, err := parsePlanRepresentation([]byte(testBaselinePlanJSON))
+	if err != nil {
+		t.Fatalf("parsePlanRepresentation() error = %v", err)
+	}
+	changed, err := parsePlanRepresentation([]byte(testChangedPlanJSON))
+	if err != nil {
+		t.Fatalf("parsePlanRepresentation() error = %v", err)
+	}
+
+	if differences := comparePlans(baseline, baseline); len(differences) != 0 {
+		t.Errorf("comparePlans() of identical plans = %v, want no differences", differences)
+	}
+
+	expected := []string{
+		"genesyscloud_group.team: missing from plan",
+		"genesyscloud_user.alice: planned values differ from baseline (email)",
+		"genesyscloud_user.bob: actions [update] differ from baseline [no-op]",
+		"genesyscloud_user.carol: not in baseline plan",
+	}
+	differences := comparePlans(baseline, changed)
+	if strings.Join(differences, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("comparePlans() = %v, want %v", differences, expected)
+	}
+}
+
+func TestBenchmark_checkPlanEquivalence(t *testing.T) {
+	baseline, _ := parsePlanRepresentation([]byte(testBaselinePlanJSON))
+	changed, _ := parsePlanRepresentation([]byte(testChangedPlanJSON))
+
+	b := &Benchmark{References: []string{"main", "v1.66.0", "feature"}, BaselineReference: "v1.66.0"}
+	data := []PlanDetails{
+		{Version: "main", Config: "small", resourceChanges: baseline},
+		{Version: "v1.66.0", Config: "small", resourceChanges: baseline},
+		{Version: "feature", Config: "small", resourceChanges: changed},
+		{Version: "feature", Config: "large", resourceChanges: changed},
+	}
+
+	b.checkPlanEquivalence(data)
+
+	if len(data[0].PlanDifferences) != 0 || len(data[1].PlanDifferences) != 0 {
+		t.Errorf("equivalent plans should not record differences: %v, %v", data[0].PlanDifferences, data[1].PlanDifferences)
+	}
+	if len(data[2].PlanDifferences) != 4 {
+		t.Errorf("PlanDifferences = %v, want 4 differences", data[2].PlanDifferences)
+	}
+	if len(data[3].PlanDifferences) != 0 {
+		t.Errorf("results without a baseline for their configuration should not be compared: %v", data[3].PlanDifferences)
+	}
+}
+
+func TestBenchmark_planFilePath(t *testing.T) {
+	b := &Benchmark{plansDir: "/test/plans"}
+	ws := &workspace{config: TfConfig{Name: "large"}}
+
+	expected := filepath.Join("/test/plans", "large", "v1.66.0.tfplan")
+	if path := b.planFilePath(ws, run{reference: "v1.66.0"}); path != expected {
+		t.Errorf("planFilePath() = %v, want %v", path, expected)
+	}
+}
+
+func TestResultKey(t *testing.T) {
+	a := PlanDetails{Config: "small", Cell: map[string]string{"parallelism": "10", "log": "DEBUG"}}
+	b := PlanDetails{Config: "small", Cell: map[string]string{"log": "DEBUG", "parallelism": "10"}}
+	c := PlanDetails{Config: "small", Cell: map[string]string{"log": "DEBUG", "parallelism": "1"}}
+
+	if resultKey(a) != resultKey(b) {
+		t.Errorf("resultKey() should not depend on map order: %v != %v", resultKey(a), resultKey(b))
+	}
+	if resultKey(a) == resultKey(c) {
+		t.Errorf("resultKey() should differ between matrix cells")
+	}
+}
@@ -0,0 +1,59 @@
+package benchmark
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// resourceHookMessage is the subset of a terraform -json message reporting the progress of a resource
+type resourceHookMessage struct {
+	Timestamp string `json:"@timestamp"`
+	Type      string `json:"type"`
+	Hook      struct {
+		Resource struct {
+			Addr         string `json:"addr"`
+			ResourceType string `json:"resource_type"`
+		} `json:"resource"`
+	} `json:"hook"`
+}
+
+// parseResourceTimings returns the seconds spent on each resource type, measured between the start and completion
+// messages terraform -json emits for every refresh and apply. Resources that never complete are not counted.
+func parseResourceTimings(r io.Reader) (map[string]float64, error) {
+	timings := make(map[string]float64)
+	started := make(map[string]time.Time)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message resourceHookMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			continue
+		}
+
+		operation, event, ok := strings.Cut(message.Type, "_")
+		if !ok || (operation != "refresh" && operation != "apply") || message.Hook.Resource.Addr == "" {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339Nano, message.Timestamp)
+		if err != nil {
+			continue
+		}
+
+		key := operation + " " + message.Hook.Resource.Addr
+		switch event {
+		case "start":
+			started[key] = timestamp
+		case "complete", "errored":
+			if start, ok := started[key]; ok {
+				timings[message.Hook.Resource.ResourceType] += timestamp.Sub(start).Seconds()
+				delete(started, key)
+			}
+		}
+	}
+
+	return timings, scanner.Err()
+}
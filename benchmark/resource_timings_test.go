@@ -0,0 +1,53 @@
+package benchmark
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseResourceTimings(t *testing.T) {
+	output := strings.Join([]string{
+		`{"@level":"info","@message":"Terraform 1.9.0","type":"version"}`,
+		`{"@timestamp":"2024-05-01T12:00:00.000000Z","type":"refresh_start","hook":{"resource":{"addr":"genesyscloud_user.alice","resource_type":"genesyscloud_user"}}}`,
+		`{"@timestamp":"2024-05-01T12:00:01.500000Z","type":"refresh_complete","hook":{"resource":{"addr":"genesyscloud_user.alice","resource_type":"genesyscloud_user"}}}`,
+		`{"@timestamp":"2024-05-01T12:00:02.000000+00:00","type":"apply_start","hook":{"resource":{"addr":"genesyscloud_user.alice","resource_type":"genesyscloud_user"}}}`,
+		`{"@timestamp":"2024-05-01T12:00:04.000000+00:00","type":"apply_complete","hook":{"resource":{"addr":"genesyscloud_user.alice","resource_type":"genesyscloud_user"}}}`,
+		`{"@timestamp":"2024-05-01T12:00:02.000000Z","type":"apply_start","hook":{"resource":{"addr":"genesyscloud_routing_queue.q","resource_type":"genesyscloud_routing_queue"}}}`,
+		`{"@timestamp":"2024-05-01T12:00:05.000000Z","type":"apply_errored","hook":{"resource":{"addr":"genesyscloud_routing_queue.q","resource_type":"genesyscloud_routing_queue"}}}`,
+		`{"@timestamp":"2024-05-01T12:00:05.000000Z","type":"apply_start","hook":{"resource":{"addr":"genesyscloud_group.g","resource_type":"genesyscloud_group"}}}`,
+		`not json`,
+	}, "\n")
+
+	timings, err := parseResourceTimings(strings.NewReader(output))
+	if err != nil {
+		t.Fatalf("parseResourceTimings() error = %v", err)
+	}
+
+	expected := map[string]float64{"genesyscloud_user": 3.5, "genesyscloud_routing_queue": 3}
+	if len(timings) != len(expected) {
+		t.Fatalf("parseResourceTimings() = %v, want %v", timings, expected)
+	}
+	for resourceType, seconds := range expected {
+		if math.Abs(timings[resourceType]-seconds) > 1e-9 {
+			t.Errorf("timings[%s] = %v, want %v", resourceType, timings[resourceType], seconds)
+		}
+	}
+}
+
+func TestSummarise(t *testing.T) {
+	s := summarise([]float64{4, 2, 6, 8})
+	if s.N != 4 || s.Mean != 5 || s.Median != 5 || s.Min != 2 || s.Max != 8 {
+		t.Errorf("summarise() = %+v", s)
+	}
+	if math.Abs(s.StdDev-math.Sqrt(20.0/3)) > 1e-9 {
+		t.Errorf("StdDev = %v, want %v", s.StdDev, math.Sqrt(20.0/3))
+	}
+
+	if s := summarise([]float64{3}); s.Median != 3 || s.StdDev != 0 {
+		t.Errorf("summarise() of one value = %+v", s)
+	}
+	if s := summarise(nil); s.N != 0 {
+		t.Errorf("summarise() of no values = %+v", s)
+	}
+}
@@ -0,0 +1,236 @@
+package benchmark
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ResultsSchemaVersion is the version of the results document written to data.json. Documents written before it was
+// versioned, which were a bare array of results, are loaded as version 1.
+const ResultsSchemaVersion = 2
+
+// redactedValue replaces the values of variables in the results
+const redactedValue = "REDACTED"
+
+// Results is the document written to data.json, describing a run of the benchmark and its results
+type Results struct {
+	SchemaVersion int `json:"schema_version"`
+
+	// RunID uniquely identifies the run
+	RunID string `json:"run_id,omitempty"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Environment describes the machine the benchmark ran on
+	Environment Environment `json:"environment"`
+
+	// Config is the effective configuration of the benchmark, after defaults were applied and with the values of
+	// Variables redacted
+	Config *Benchmark `json:"config,omitempty"`
+
+	// Results holds a result per reference, configuration and matrix cell
+	Results []PlanDetails `json:"results"`
+
+	// Cleanup records the outcome of the final destroy for each workspace, when FinalDestroy is set
+	Cleanup []CleanupDetails `json:"cleanup,omitempty"`
+}
+
+// Environment describes the machine and tools a benchmark ran with
+type Environment struct {
+	Hostname string `json:"hostname,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	Kernel   string `json:"kernel,omitempty"`
+	CPU      string `json:"cpu,omitempty"`
+	CPUs     int    `json:"cpus,omitempty"`
+
+	// GoVersion is the version of Go the benchmark was built with
+	GoVersion string `json:"go_version,omitempty"`
+
+	TerraformVersion string `json:"terraform_version,omitempty"`
+}
+
+// Baseline returns the reference other references are compared against: the configured BaselineReference, or the
+// reference of the first result
+func (r *Results) Baseline() string {
+	if r.Config != nil && r.Config.BaselineReference != "" {
+		return r.Config.BaselineReference
+	}
+	if len(r.Results) == 0 {
+		return ""
+	}
+	return r.Results[0].Version
+}
+
+// Compare compares every result with the result of the baseline reference for the same configuration and matrix cell.
+// The baseline defaults to Baseline when empty.
+func (r *Results) Compare(baseline string) []Comparison {
+	if baseline == "" {
+		baseline = r.Baseline()
+	}
+	return compareResults(r.Results, baseline)
+}
+
+// Failures returns the results that failed, were slower than MaxRegressionPercent allows, were not idempotent or
+// planned differently from the baseline
+func (r *Results) Failures() []PlanDetails {
+	var failures []PlanDetails
+	for _, plan := range r.Results {
+		if plan.Failed() {
+			failures = append(failures, plan)
+		}
+	}
+	return failures
+}
+
+// LoadResults reads a results document from a file, see ReadResults
+func LoadResults(path string) (*Results, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	results, err := ReadResults(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results from %s: %w", path, err)
+	}
+	return results, nil
+}
+
+// ReadResults reads a results document. Legacy documents holding only an array of results are returned with
+// SchemaVersion 1 and no run metadata.
+func ReadResults(r io.Reader) (*Results, error) {
+	reader := bufio.NewReader(r)
+	first, err := firstNonSpace(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(reader)
+	if first == '[' {
+		results := &Results{SchemaVersion: 1}
+		if err := decoder.Decode(&results.Results); err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	var results Results
+	if err := decoder.Decode(&results); err != nil {
+		return nil, err
+	}
+	if results.SchemaVersion < 2 || results.SchemaVersion > ResultsSchemaVersion {
+		return nil, fmt.Errorf("unsupported results schema version %d", results.SchemaVersion)
+	}
+	return &results, nil
+}
+
+// firstNonSpace returns the first byte that is not white space without consuming it
+func firstNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+// newResults returns the results document for a run. The values of Variables and of -var flags in Args are redacted, as
+// they commonly hold credentials.
+func (b *Benchmark) newResults(start time.Time, data []PlanDetails) *Results {
+	config := *b
+	config.Variables = redactVariables(b.Variables)
+	config.Args = redactCommand(b.Args)
+	return &Results{
+		SchemaVersion: ResultsSchemaVersion,
+		RunID:         b.runID,
+		Start:         start,
+		End:           time.Now(),
+		Environment:   b.environment,
+		Config:        &config,
+		Results:       data,
+		Cleanup:       b.cleanupDetails,
+	}
+}
+
+// newRunID returns a random identifier for a run
+func newRunID() string {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(id)
+}
+
+// collectEnvironment describes the machine and the terraform binary the benchmark runs with. Details that cannot be
+// determined are left empty.
+func (b *Benchmark) collectEnvironment() Environment {
+	env := Environment{
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+		CPUs:      runtime.NumCPU(),
+		GoVersion: runtime.Version(),
+	}
+	env.Hostname, _ = os.Hostname()
+	env.Kernel = kernelVersion()
+	env.CPU = cpuModel()
+
+	output, err := exec.Command("terraform", "version", "-json").Output()
+	if err != nil {
+		b.logMessage(LogLevelDebug, "Failed to determine the terraform version: %v", err)
+		return env
+	}
+	var version struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(output, &version); err != nil {
+		b.logMessage(LogLevelDebug, "Failed to parse the terraform version: %v", err)
+		return env
+	}
+	env.TerraformVersion = version.TerraformVersion
+	return env
+}
+
+// redactVariables returns variables with every value replaced by redactedValue, keeping the names
+func redactVariables(variables map[string]string) map[string]string {
+	if variables == nil {
+		return nil
+	}
+	redacted := make(map[string]string, len(variables))
+	for name := range variables {
+		redacted[name] = redactedValue
+	}
+	return redacted
+}
+
+// redactCommand returns a copy of a command line with the value of every -var flag replaced by redactedValue, keeping
+// the variable names
+func redactCommand(command []string) []string {
+	redacted := slices.Clone(command)
+	for i, arg := range redacted {
+		if value, ok := strings.CutPrefix(arg, "-var="); ok {
+			name, _, _ := strings.Cut(value, "=")
+			redacted[i] = "-var=" + name + "=" + redactedValue
+		} else if arg == "-var" && i+1 < len(redacted) {
+			name, _, _ := strings.Cut(redacted[i+1], "=")
+			redacted[i+1] = name + "=" + redactedValue
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,175 @@
+package benchmark
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadResults(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	written := &Results{
+		SchemaVersion: ResultsSchemaVersion,
+		RunID:         "0a1b2c3d",
+		Start:         start,
+		End:           start.Add(time.Minute),
+		Environment:   Environment{Hostname: "perf-runner-1", OS: "linux", TerraformVersion: "1.9.0"},
+		Config:        &Benchmark{TfCommand: Plan, References: []string{"main", "v1.66.0"}, Iterations: 5, ResultWriters: []ResultWriter{CSVWriter{}}},
+		Results:       testResults(),
+	}
+
+	var buf bytes.Buffer
+	if err := (JSONWriter{}).Write(&buf, written); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	results, err := ReadResults(&buf)
+	if err != nil {
+		t.Fatalf("ReadResults() error = %v", err)
+	}
+	if results.RunID != "0a1b2c3d" || !results.Start.Equal(start) || results.Environment.TerraformVersion != "1.9.0" {
+		t.Errorf("unexpected run metadata %+v", results)
+	}
+	if results.Config == nil || results.Config.TfCommand != Plan || results.Config.Iterations != 5 || len(results.Config.References) != 2 {
+		t.Errorf("unexpected config %+v", results.Config)
+	}
+	if len(results.Results) != 1 || len(results.Results[0].Samples) != 2 {
+		t.Errorf("unexpected results %+v", results.Results)
+	}
+}
+
+func TestReadResults_Legacy(t *testing.T) {
+	legacy := `
+	[
+		{"version": "main", "duration": 12.345, "command": ["terraform", "plan"]},
+		{"version": "v1.66.0", "duration": 11.234, "command": ["terraform", "plan"]}
+	]`
+
+	results, err := ReadResults(strings.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("ReadResults() error = %v", err)
+	}
+	if results.SchemaVersion != 1 || len(results.Results) != 2 || results.Results[1].Duration != 11.234 {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestReadResults_UnsupportedVersion(t *testing.T) {
+	for _, document := range []string{`{"schema_version": 99, "results": []}`, `{"results": []}`, ``} {
+		if _, err := ReadResults(strings.NewReader(document)); err == nil {
+			t.Errorf("expected error reading %q", document)
+		}
+	}
+}
+
+func TestLoadResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := os.WriteFile(path, []byte(`[{"version": "main", "duration": 1}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := LoadResults(path)
+	if err != nil || len(results.Results) != 1 {
+		t.Errorf("LoadResults() = %+v, %v", results, err)
+	}
+
+	if _, err := LoadResults(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("expected error loading a missing file")
+	}
+}
+
+func TestResults_Compare(t *testing.T) {
+	results := &Results{Results: []PlanDetails{
+		{Version: "main", Samples: samplesOf(10, 10.1, 9.9, 10, 10.2)},
+		{Version: "feature", Samples: samplesOf(8, 8.1, 7.9, 8, 8.2)},
+		{Version: "other", Samples: samplesOf(10)},
+		// Failed results are not compared, as their timings are incomplete
+		{Version: "broken", Failure: &Failure{Phase: phaseCommand, Iteration: 1}},
+	}}
+
+	if baseline := results.Baseline(); baseline != "main" {
+		t.Errorf("Baseline() = %v, want main", baseline)
+	}
+
+	comparisons := results.Compare("")
+	if len(comparisons) != 2 || !comparisons[0].Improvement() || comparisons[1].Significant() {
+		t.Errorf("unexpected comparisons %+v", comparisons)
+	}
+
+	results.Config = &Benchmark{BaselineReference: "other"}
+	if comparisons := results.Compare(""); len(comparisons) != 2 || comparisons[0].Baseline.Version != "other" {
+		t.Errorf("expected comparisons against the configured baseline, got %+v", comparisons)
+	}
+
+	failures := results.Failures()
+	if len(failures) != 1 || failures[0].Version != "broken" {
+		t.Errorf("Failures() = %+v", failures)
+	}
+}
+
+func TestBenchmark_RunWithResults_InvalidConfiguration(t *testing.T) {
+	b := &Benchmark{LogLevel: LogLevelQuiet}
+
+	results, err := b.RunWithResults()
+	if err == nil || results != nil {
+		t.Errorf("RunWithResults() = %v, %v, want validation error", results, err)
+	}
+}
+
+func TestBenchmark_newResults_redactsVariables(t *testing.T) {
+	b := &Benchmark{TfCommand: Plan, Variables: map[string]string{"client_secret": "s3cr3t", "region": "us-east-1"},
+		Args: []string{"-parallelism=20", "-var=api_token=t0k3n"}}
+
+	results := b.newResults(time.Now(), nil)
+	for name, value := range results.Config.Variables {
+		if value != redactedValue {
+			t.Errorf("Variables[%s] = %v, want %v", name, value, redactedValue)
+		}
+	}
+	if len(results.Config.Variables) != 2 {
+		t.Errorf("Variables = %v, want both names kept", results.Config.Variables)
+	}
+	if b.Variables["client_secret"] != "s3cr3t" {
+		t.Error("newResults() redacted the benchmark's own Variables")
+	}
+	if args := strings.Join(results.Config.Args, " "); args != "-parallelism=20 -var=api_token=REDACTED" {
+		t.Errorf("Args = %v, want the -var value redacted", args)
+	}
+	if b.Args[1] != "-var=api_token=t0k3n" {
+		t.Error("newResults() redacted the benchmark's own Args")
+	}
+}
+
+func TestBenchmark_apply_redactsLoggedCommand(t *testing.T) {
+	installFakeCommand(t, "terraform", `exit 0
+`)
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	b := &Benchmark{LogLevel: LogLevelInfo, Variables: map[string]string{"client_secret": "s3cr3t"}}
+	ws := &workspace{dir: t.TempDir()}
+	if err := b.apply(ws, filepath.Join(t.TempDir(), "apply.log")); err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+
+	if strings.Contains(output.String(), "s3cr3t") || !strings.Contains(output.String(), "-var=client_secret=REDACTED") {
+		t.Errorf("expected the logged command to be redacted, got:\n%s", output.String())
+	}
+}
+
+func TestRedactCommand(t *testing.T) {
+	command := []string{"terraform", "plan", "-var=client_secret=s3cr3t", "-var-file=vars.tfvars", "-var", "region=us-east-1"}
+
+	expected := "terraform plan -var=client_secret=REDACTED -var-file=vars.tfvars -var region=REDACTED"
+	if redacted := strings.Join(redactCommand(command), " "); redacted != expected {
+		t.Errorf("redactCommand() = %v, want %v", redacted, expected)
+	}
+	if command[2] != "-var=client_secret=s3cr3t" {
+		t.Error("redactCommand() modified its argument")
+	}
+}
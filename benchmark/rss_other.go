@@ -0,0 +1,10 @@
+//go:build !unix
+
+package benchmark
+
+import "os"
+
+// peakRSSBytes returns 0 as the platform does not report the peak memory of child processes
+func peakRSSBytes(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,16 @@
+//go:build unix && !darwin
+
+package benchmark
+
+import (
+	"os"
+	"syscall"
+)
+
+// peakRSSBytes returns the maximum resident set size of an exited process, which these platforms report in kilobytes
+func peakRSSBytes(state *os.ProcessState) int64 {
+	if usage, ok := state.SysUsage().(*syscall.Rusage); ok {
+		return int64(usage.Maxrss) * 1024
+	}
+	return 0
+}
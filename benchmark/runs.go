@@ -0,0 +1,92 @@
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	// latestRunLinkName is the symlink in OutputDir to the directory of the most recent run
+	latestRunLinkName = "latest"
+
+	// runDirTimeFormat is the UTC start time at the beginning of a run directory's name
+	runDirTimeFormat = "20060102-150405"
+)
+
+// runDirPattern matches the names of run directories, so that pruning never touches anything else in OutputDir
+var runDirPattern = regexp.MustCompile(`^\d{8}-\d{6}(-[0-9a-f]+)?$`)
+
+// runDirName returns the name of the directory a run started at start is written to, e.g. 20240501-120000-0a1b2c3d
+func runDirName(start time.Time, runID string) string {
+	name := start.UTC().Format(runDirTimeFormat)
+	if runID != "" {
+		name += "-" + runID
+	}
+	return name
+}
+
+// linkLatestRun points the latest symlink in OutputDir at the current run directory, replacing it atomically so that
+// it is never missing while a previous run is linked
+func (b *Benchmark) linkLatestRun() error {
+	outputDir := filepath.Dir(b.runDir)
+	link := filepath.Join(outputDir, latestRunLinkName)
+	temporary := link + ".tmp-" + filepath.Base(b.runDir)
+
+	os.Remove(temporary)
+	if err := os.Symlink(filepath.Base(b.runDir), temporary); err != nil {
+		return fmt.Errorf("failed to create %s link: %w", latestRunLinkName, err)
+	}
+	if err := os.Rename(temporary, link); err != nil {
+		os.Remove(temporary)
+		return fmt.Errorf("failed to replace %s link: %w", link, err)
+	}
+	return nil
+}
+
+// pruneRuns removes the run directories in OutputDir beyond the KeepRuns most recent and those that started more than
+// KeepDays ago. The current run is always kept.
+func (b *Benchmark) pruneRuns() error {
+	if b.KeepRuns == 0 && b.KeepDays == 0 {
+		return nil
+	}
+
+	outputDir := filepath.Dir(b.runDir)
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to list runs in %s: %w", outputDir, err)
+	}
+
+	var runs []string
+	for _, entry := range entries {
+		if entry.IsDir() && runDirPattern.MatchString(entry.Name()) {
+			runs = append(runs, entry.Name())
+		}
+	}
+	// Names begin with the start time, so they sort oldest first
+	sort.Sort(sort.Reverse(sort.StringSlice(runs)))
+
+	cutoff := time.Now().AddDate(0, 0, -b.KeepDays)
+	for i, name := range runs {
+		if name == filepath.Base(b.runDir) {
+			continue
+		}
+
+		expired := b.KeepRuns > 0 && i >= b.KeepRuns
+		if start, err := time.Parse(runDirTimeFormat, name[:len(runDirTimeFormat)]); err == nil && b.KeepDays > 0 && start.Before(cutoff) {
+			expired = true
+		}
+		if !expired {
+			continue
+		}
+
+		b.logMessage(LogLevelInfo, "🧹 Removing previous run %s", name)
+		if err := os.RemoveAll(filepath.Join(outputDir, name)); err != nil {
+			return fmt.Errorf("failed to remove run %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRunDirName(t *testing.T) {
+	start := time.Date(2024, 5, 1, 14, 30, 5, 0, time.FixedZone("CEST", 2*60*60))
+	if name := runDirName(start, "0a1b2c3d"); name != "20240501-123005-0a1b2c3d" {
+		t.Errorf("runDirName() = %q", name)
+	}
+	if name := runDirName(start, ""); name != "20240501-123005" || !runDirPattern.MatchString(name) {
+		t.Errorf("runDirName() without a run ID = %q", name)
+	}
+}
+
+func TestBenchmark_linkLatestRun(t *testing.T) {
+	outputDir := t.TempDir()
+	for _, name := range []string{"20240501-120000-aaaa1111", "20240502-120000-bbbb2222"} {
+		b := &Benchmark{runDir: filepath.Join(outputDir, name)}
+		if err := os.MkdirAll(b.runDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.linkLatestRun(); err != nil {
+			t.Fatalf("linkLatestRun() error = %v", err)
+		}
+	}
+
+	target, err := os.Readlink(filepath.Join(outputDir, latestRunLinkName))
+	if err != nil || target != "20240502-120000-bbbb2222" {
+		t.Errorf("latest links to %q, %v, want the most recent run", target, err)
+	}
+}
+
+func TestBenchmark_pruneRuns(t *testing.T) {
+	now := time.Now().UTC()
+	runs := []string{
+		runDirName(now.AddDate(0, 0, -40), "00000001"),
+		runDirName(now.AddDate(0, 0, -20), "00000002"),
+		runDirName(now.AddDate(0, 0, -2), "00000003"),
+		runDirName(now.AddDate(0, 0, -1), "00000004"),
+	}
+	current := runDirName(now, "00000005")
+
+	tests := []struct {
+		name     string
+		keepRuns int
+		keepDays int
+		expected []string
+	}{
+		{name: "keep everything", expected: append(slices.Clone(runs), current)},
+		{name: "keep last runs", keepRuns: 2, expected: []string{runs[3], current}},
+		{name: "keep days", keepDays: 30, expected: []string{runs[1], runs[2], runs[3], current}},
+		{name: "keep both", keepRuns: 4, keepDays: 10, expected: []string{runs[2], runs[3], current}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputDir := t.TempDir()
+			for _, name := range append([]string{"history", "logs"}, append(runs, current)...) {
+				if err := os.Mkdir(filepath.Join(outputDir, name), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			b := &Benchmark{KeepRuns: tt.keepRuns, KeepDays: tt.keepDays, runDir: filepath.Join(outputDir, current)}
+			if err := b.pruneRuns(); err != nil {
+				t.Fatalf("pruneRuns() error = %v", err)
+			}
+
+			entries, err := os.ReadDir(outputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var remaining []string
+			for _, entry := range entries {
+				if runDirPattern.MatchString(entry.Name()) {
+					remaining = append(remaining, entry.Name())
+				}
+			}
+			if !slices.Equal(remaining, tt.expected) {
+				t.Errorf("remaining runs = %v, want %v", remaining, tt.expected)
+			}
+
+			// Anything else in the output directory is left alone
+			for _, name := range []string{"history", "logs"} {
+				if _, err := os.Stat(filepath.Join(outputDir, name)); err != nil {
+					t.Errorf("%s was removed: %v", name, err)
+				}
+			}
+		})
+	}
+}
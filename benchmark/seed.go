@@ -0,0 +1,97 @@
+package benchmark
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	seedLogFileName = "seed.log"
+)
+
+// seedWorkspaces applies the seed reference in every workspace and snapshots the resulting state. With
+// IsolationPerReference the seed is applied in a dedicated temporary workspace which is returned so that it can be
+// released, destroying the seeded infrastructure, once the benchmark is complete.
+func (b *Benchmark) seedWorkspaces(workspaces []*workspace) (seeds []*workspace, err error) {
+	b.logMessage(LogLevelInfo, "🌱 Seeding state with reference %s", b.SeedReference)
+	if _, err := b.makeSideload(b.SeedReference, seedLogName(b.SeedReference)); err != nil {
+		return nil, appendLogTail(err, b.stepLogFilePath(seedLogName(b.SeedReference), failurePhase(err)))
+	}
+
+	if err := os.MkdirAll(b.stateDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory %s: %w", b.stateDir, err)
+	}
+
+	for _, ws := range workspaces {
+		target := ws
+		if b.Isolation == IsolationPerReference {
+			override := b.referenceOverride(ws.config, b.SeedReference)
+			if target, err = b.newTemporaryWorkspace(ws, seedLogName(b.SeedReference), override, false); err != nil {
+				return seeds, err
+			}
+			seeds = append(seeds, target)
+		}
+		target.seed = true
+
+		if err := b.seed(target); err != nil {
+			return seeds, fmt.Errorf("failed to seed %s: %w", ws, err)
+		}
+
+		// Workspaces copied from this one restore the same snapshot
+		ws.snapshot = target.snapshot
+	}
+	return seeds, nil
+}
+
+// seed applies the configuration in the workspace and saves its state to the state directory
+func (b *Benchmark) seed(ws *workspace) error {
+	command := b.buildCommand(ws, Apply, false)
+	b.logMessage(LogLevelInfo, "🌱 Running %v in directory %s", redactCommand(command), ws.dir)
+	if err := b.runSetupCommand(ws, command, ws.logFilePath(seedLogFileName)); err != nil {
+		return appendLogTail(fmt.Errorf("seed apply failed: %v", err), ws.logFilePath(seedLogFileName))
+	}
+
+	name := ws.config.Name
+	if name == "" {
+		name = "default"
+	}
+	snapshot, err := filepath.Abs(filepath.Join(b.stateDir, name+".tfstate"))
+	if err != nil {
+		return fmt.Errorf("failed to resolve snapshot path: %w", err)
+	}
+
+	snapshotFile, err := os.Create(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	defer snapshotFile.Close()
+
+	logFile, err := os.OpenFile(ws.logFilePath(seedLogFileName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %v", err)
+	}
+	defer logFile.Close()
+
+	cmd := b.setupTerraformCommand(ws.dir, []string{"terraform", "state", "pull"}, logFile, true, ws.env...)
+	cmd.Stdout = snapshotFile
+	if err := b.runCommand(cmd); err != nil {
+		return fmt.Errorf("terraform state pull failed: %v", err)
+	}
+
+	b.logMessage(LogLevelInfo, "🌱 State snapshot written to %s", snapshot)
+	ws.snapshot = snapshot
+	return nil
+}
+
+// restoreSnapshot pushes the seeded state snapshot into the workspace, replacing whatever state it holds, writing the
+// output to outputPath
+func (b *Benchmark) restoreSnapshot(ws *workspace, outputPath string) error {
+	command := []string{"terraform", "state", "push", "-force", ws.snapshot}
+	b.logMessage(LogLevelInfo, "🌱 Restoring state snapshot %s in directory %s", ws.snapshot, ws.dir)
+
+	if err := b.runSetupCommand(ws, command, outputPath); err != nil {
+		return fmt.Errorf("terraform state push failed: %v", err)
+	}
+	return nil
+}
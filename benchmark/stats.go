@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"math"
+	"slices"
+	"sort"
+)
+
+// Statistics describes the distribution of a set of durations in seconds
+type Statistics struct {
+	// N is the number of durations
+	N int `json:"n"`
+
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+
+	// StdDev is the sample standard deviation
+	StdDev float64 `json:"stddev"`
+
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// summarise returns the statistics of values
+func summarise(values []float64) Statistics {
+	if len(values) == 0 {
+		return Statistics{}
+	}
+
+	sorted := slices.Clone(values)
+	sort.Float64s(sorted)
+
+	s := Statistics{N: len(sorted), Min: sorted[0], Max: sorted[len(sorted)-1]}
+	for _, v := range sorted {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+
+	if s.N%2 == 1 {
+		s.Median = sorted[s.N/2]
+	} else {
+		s.Median = (sorted[s.N/2-1] + sorted[s.N/2]) / 2
+	}
+
+	if s.N > 1 {
+		var squares float64
+		for _, v := range sorted {
+			squares += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(squares / float64(s.N-1))
+	}
+	return s
+}
+
+// commandDurations returns the duration of the measured command in each iteration
+func commandDurations(plan PlanDetails) []float64 {
+	phases := commandPhases(plan)
+	durations := make([]float64, len(phases))
+	for i, phase := range phases {
+		durations[i] = phase.Duration
+	}
+	return durations
+}
+
+// meanPhases returns the setup phases followed by the mean duration and CPU time of each phase across iterations, in
+// the order the phases first ran. Peak memory is the largest of any iteration, and API calls are the mean rounded down.
+func meanPhases(plan PlanDetails) []Phase {
+	phases := append([]Phase{}, plan.Setup...)
+
+	var names []string
+	totals := make(map[string]*Phase)
+	for _, sample := range plan.Samples {
+		for _, phase := range sample.Phases {
+			total, ok := totals[phase.Name]
+			if !ok {
+				total = &Phase{Name: phase.Name}
+				totals[phase.Name] = total
+				names = append(names, phase.Name)
+			}
+			total.Duration += phase.Duration
+			total.UserCPU += phase.UserCPU
+			total.SystemCPU += phase.SystemCPU
+			total.PeakRSSBytes = max(total.PeakRSSBytes, phase.PeakRSSBytes)
+			total.APICalls += phase.APICalls
+		}
+	}
+	for _, name := range names {
+		total := totals[name]
+		n := float64(len(plan.Samples))
+		phases = append(phases, Phase{
+			Name:         name,
+			Duration:     total.Duration / n,
+			UserCPU:      total.UserCPU / n,
+			SystemCPU:    total.SystemCPU / n,
+			PeakRSSBytes: total.PeakRSSBytes,
+			APICalls:     total.APICalls / int64(len(plan.Samples)),
+		})
+	}
+	return phases
+}
+
+// meanResources returns the mean seconds spent on each resource type across the iterations that recorded them
+func meanResources(plan PlanDetails) map[string]float64 {
+	means := make(map[string]float64)
+	count := 0
+	for _, sample := range plan.Samples {
+		if sample.Resources == nil {
+			continue
+		}
+		count++
+		for resourceType, seconds := range sample.Resources {
+			means[resourceType] += seconds
+		}
+	}
+	for resourceType := range means {
+		means[resourceType] /= float64(count)
+	}
+	return means
+}
+
+// scenarioName describes what a result was measured against: its configuration and matrix cell, e.g.
+// "small-org [parallelism=10]". It is empty when neither TfConfigs nor Matrix is used.
+func scenarioName(plan PlanDetails) string {
+	name := plan.Config
+	if cell := formatCell(plan.Cell); cell != "" {
+		if name != "" {
+			name += " "
+		}
+		name += "[" + cell + "]"
+	}
+	return name
+}
+
+// resultName returns the reference followed by the scenario, if any
+func resultName(plan PlanDetails) string {
+	if scenario := scenarioName(plan); scenario != "" {
+		return plan.Version + " " + scenario
+	}
+	return plan.Version
+}
@@ -0,0 +1,27 @@
+//go:build darwin
+
+package benchmark
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// kernelVersion returns the release of the running kernel
+func kernelVersion() string {
+	return sysctl("kern.osrelease")
+}
+
+// cpuModel returns the brand name of the CPU
+func cpuModel() string {
+	return sysctl("machdep.cpu.brand_string")
+}
+
+// sysctl returns the value of a kernel state variable, or "" if it cannot be read
+func sysctl(name string) string {
+	output, err := exec.Command("sysctl", "-n", name).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package benchmark
+
+// kernelVersion is not reported on this platform
+func kernelVersion() string {
+	return ""
+}
+
+// cpuModel is not reported on this platform
+func cpuModel() string {
+	return ""
+}
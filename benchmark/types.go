@@ -1,13 +1,66 @@
 package benchmark
 
+import "time"
+
 type command string
 
 const (
-	Apply command = "terraform apply --auto-approve"
-	Init  command = "terraform init"
-	Plan  command = "terraform plan"
+	Apply            command = "terraform apply --auto-approve"
+	ApplyRefreshOnly command = "terraform apply -refresh-only --auto-approve"
+	Destroy          command = "terraform destroy --auto-approve"
+	Import           command = "terraform import"
+	Init             command = "terraform init"
+	Plan             command = "terraform plan"
+	PlanRefreshOnly  command = "terraform plan -refresh-only"
+	ProvidersSchema  command = "terraform providers schema -json"
+	Test             command = "terraform test"
+	Validate         command = "terraform validate"
+
+	// stateRm removes the resource imported by Import from the state so that it can be imported again. It is only run
+	// before the measured command.
+	stateRm command = "terraform state rm"
 )
 
+// commandSpec describes how a terraform command behaves so the benchmark knows how to prepare for it
+type commandSpec struct {
+	// name is a short identifier for the command
+	name string
+
+	// mutatesState is true when the command can create, change or destroy infrastructure or state
+	mutatesState bool
+
+	// preRun is run before the measured command so that every reference starts from the same state (empty for none)
+	preRun command
+
+	// acceptsVariables is true when the command accepts -var and -var-file flags
+	acceptsVariables bool
+
+	// streamsJSON is true when the command accepts -json and reports the progress of each resource
+	streamsJSON bool
+}
+
+var commandSpecs = map[command]commandSpec{
+	Apply:            {name: "Apply", mutatesState: true, preRun: Destroy, acceptsVariables: true, streamsJSON: true},
+	ApplyRefreshOnly: {name: "ApplyRefreshOnly", mutatesState: true, acceptsVariables: true, streamsJSON: true},
+	Destroy:          {name: "Destroy", mutatesState: true, preRun: Apply, acceptsVariables: true, streamsJSON: true},
+	Import:           {name: "Import", mutatesState: true, preRun: stateRm, acceptsVariables: true},
+	Init:             {name: "Init", acceptsVariables: true},
+	Plan:             {name: "Plan", acceptsVariables: true, streamsJSON: true},
+	PlanRefreshOnly:  {name: "PlanRefreshOnly", acceptsVariables: true, streamsJSON: true},
+	ProvidersSchema:  {name: "ProvidersSchema"},
+	Test:             {name: "Test", mutatesState: true, acceptsVariables: true},
+	Validate:         {name: "Validate"},
+}
+
+// spec returns the metadata for the command. Unknown commands are assumed to mutate state and are
+// preceded by a destroy, which is the safest behaviour.
+func (c command) spec() commandSpec {
+	if spec, ok := commandSpecs[c]; ok {
+		return spec
+	}
+	return commandSpec{name: "Custom", mutatesState: true, preRun: Destroy, acceptsVariables: true}
+}
+
 // LogLevel represents the logging level
 type LogLevel int
 
@@ -22,6 +75,25 @@ func (l LogLevel) String() string {
 	return []string{"Quiet", "Info", "Debug"}[l]
 }
 
+// IsolationMode controls whether commands run in the configuration directory or in a temporary copy of it
+type IsolationMode int
+
+const (
+	// IsolationNone runs commands directly in the configuration directory, sharing its .terraform directory and state
+	IsolationNone IsolationMode = iota
+
+	// IsolationPerRun copies each configuration into a temporary directory with its own local state for the whole benchmark
+	IsolationPerRun
+
+	// IsolationPerReference copies each configuration into a fresh temporary directory with its own local state for every reference
+	IsolationPerReference
+)
+
+// String returns the string representation of the IsolationMode
+func (i IsolationMode) String() string {
+	return []string{"None", "PerRun", "PerReference"}[i]
+}
+
 type Benchmark struct {
 	// TfCommand Terraform command to run
 	TfCommand command
@@ -35,30 +107,301 @@ type Benchmark struct {
 	// SkipDestroyConfirmation controls whether to skip user confirmation for destructive operations
 	SkipDestroyConfirmation bool
 
+	// BaselineReference is the reference other references are compared against (Defaults to the first reference)
+	BaselineReference string
+
+	// CheckPlanEquivalence saves the plan of each reference in Plan benchmarks and reports any reference whose planned
+	// actions or values differ from the baseline reference
+	CheckPlanEquivalence bool
+
+	// CheckIdempotency runs terraform plan after each measured Apply and records whether it was empty, flagging references
+	// whose apply leaves a perpetual diff
+	CheckIdempotency bool
+
+	// FinalDestroy destroys everything in each configuration's state once the benchmark completes, fails or is cancelled,
+	// and verifies that no resources were left behind
+	FinalDestroy bool
+
+	// MaxDestroyResources is the maximum number of resources a destroy run by the benchmark may remove (Defaults to 0, no limit)
+	MaxDestroyResources int
+
+	// DestroyAllowlist lists the resource types and addresses a destroy run by the benchmark may remove (Defaults to allowing everything)
+	DestroyAllowlist []string
+
 	// LogLevel controls the verbosity of logging
 	LogLevel LogLevel
 
 	// TerraformRcFilePath is the path to the .terraformrc file (Defaults to "./.terraformrc" which is to say we assume it is in the current working directory)
 	TerraformRcFilePath string
 
-	// OutputDir is the directory to write the output to (Defaults to "output")
+	// OutputDir is the directory to write the output to (Defaults to "output"). Each run is written to its own
+	// directory in it, named after the run's start time and ID, and the latest symlink points to the most recent.
 	OutputDir string
 
+	// KeepRuns is the number of most recent run directories kept in OutputDir, removing older ones when a run starts
+	// (Defaults to 0, keep all)
+	KeepRuns int
+
+	// KeepDays removes run directories in OutputDir that started more than this many days ago when a run starts
+	// (Defaults to 0, keep all)
+	KeepDays int
+
+	// HistoryDir is the directory of the history that the results of every run are appended to (Defaults to "history"
+	// in OutputDir)
+	HistoryDir string
+
 	// TfConfigDir is the directory containing the Terraform configuration to run commands against (Defaults to current working directory)
 	TfConfigDir string
 
+	// TfConfigs lists named Terraform configurations to benchmark in turn, as an alternative to TfConfigDir
+	TfConfigs []TfConfig
+
+	// Isolation controls whether commands run in the configuration directory or in a temporary copy with its own local
+	// state (Defaults to IsolationNone)
+	Isolation IsolationMode
+
+	// SeedReference is applied once before the benchmark and the resulting state is snapshotted and restored before
+	// every measured command, so that all references start from identical infrastructure and state
+	SeedReference string
+
+	// ReferenceOverrides swap or overlay the Terraform configuration for matching references, e.g. older tags that do not
+	// support attributes used by the current configuration. The first matching override is used.
+	ReferenceOverrides []ReferenceOverride
+
+	// Args are extra arguments appended to the measured Terraform command, e.g. "-parallelism=20" or the address and ID for Import
+	Args []string
+
+	// Variables are passed as -var flags to every Terraform command that accepts them
+	Variables map[string]string
+
+	// VarFiles are passed as -var-file flags to every Terraform command that accepts them (relative paths are resolved against each configuration directory)
+	VarFiles []string
+
+	// Iterations is the number of times the command is measured for each reference, configuration and matrix cell (Defaults to 1)
+	Iterations int
+
+	// ResultWriters write the results in additional formats alongside data.json, e.g. CSVWriter or TSVWriter
+	ResultWriters []ResultWriter `json:"-"`
+
+	// ResourceTimings runs the measured command with -json and records the time spent on each resource type. Only
+	// supported by plan, apply and destroy.
+	ResourceTimings bool
+
+	// HTMLReport writes a self-contained report.html with tables and charts of the results to the run directory
+	HTMLReport bool
+
+	// ContinueOnFailure records a failing step on the result it belongs to and carries on with the next matrix cell,
+	// configuration or reference. The benchmark still returns an error once every result has been written.
+	ContinueOnFailure bool
+
+	// MaxRegressionPercent fails the benchmark when a reference is significantly slower than the baseline reference by
+	// more than this percentage of the median duration (Defaults to 0, disabled). Requires at least 4 Iterations.
+	MaxRegressionPercent float64
+
+	// MarkdownReport writes report.md to the run directory, comparing every reference with the baseline reference in a
+	// form suitable for posting as a pull request comment
+	MarkdownReport bool
+
+	// MarkdownReportMaxBytes limits the size of report.md, omitting details and then table rows as needed (Defaults to
+	// 65536, the maximum length of a GitHub comment)
+	MarkdownReportMaxBytes int
+
+	// Matrix lists the axes to benchmark every reference under. Each reference is run once for every combination of axis values.
+	Matrix []MatrixAxis
+
 	// RequireConfirmation controls whether to require user confirmation for destructive operations (Deprecated. Use SkipDestroyConfirmation instead.)
 	RequireConfirmation bool
 
-	logsDir             string
-	performanceDir      string
-	performanceFilePath string
-	destroyLogFilePath  string
-	initLogFilePath     string
+	runDir         string
+	logsDir        string
+	performanceDir string
+	stateDir       string
+	plansDir       string
+	historyDir     string
+	runID          string
+	environment    Environment
+	cleanupDetails []CleanupDetails
+	currentPhase   *Phase
+}
+
+// TfConfig is a named Terraform configuration to benchmark
+type TfConfig struct {
+	// Name identifies the configuration in results and is used as a directory name in the run directory, so it cannot
+	// contain path separators
+	Name string
+
+	// Dir is the directory containing the Terraform configuration
+	Dir string
+}
+
+// ReferenceOverride replaces or overlays the Terraform configuration for references matching Pattern. Overrides are
+// applied to a temporary copy of the configuration so the original directory is never modified.
+type ReferenceOverride struct {
+	// Pattern is matched against the reference using path.Match, e.g. "v1.4*"
+	Pattern string
+
+	// Config restricts the override to the named configuration in TfConfigs (Defaults to every configuration)
+	Config string
+
+	// ConfigDir is a directory containing a complete configuration used instead of the original
+	ConfigDir string
+
+	// OverlayDir is a directory of files copied over the configuration, replacing files with the same name
+	OverlayDir string
+}
+
+// MatrixAxis is a dimension of the benchmark matrix whose values are passed to the measured command as a flag or environment variable
+type MatrixAxis struct {
+	// Name identifies the axis in results and log file names
+	Name string
+
+	// Flag is the Terraform flag the values are passed to, e.g. "-parallelism" (mutually exclusive with EnvVar)
+	Flag string
+
+	// EnvVar is the environment variable the values are assigned to, e.g. "TF_LOG" (mutually exclusive with Flag)
+	EnvVar string
+
+	// Values are the values to benchmark
+	Values []string
+}
+
+// IdempotencyDetails records the outcome of the plan run after a measured apply
+type IdempotencyDetails struct {
+	// Empty is true when the plan proposed no changes
+	Empty bool `json:"empty"`
+
+	// PendingChanges is the number of resource changes the plan proposed. A plan that is not empty may propose none,
+	// e.g. when only outputs change.
+	PendingChanges int `json:"pending_changes"`
+
+	// Addresses lists the addresses of the resources with pending changes
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// CleanupDetails records the outcome of the final destroy for a configuration
+type CleanupDetails struct {
+	// Config is the name of the Terraform configuration, when TfConfigs is used
+	Config string `json:"config,omitempty"`
+
+	// Remaining lists the addresses of resources still in state after the destroy
+	Remaining []string `json:"remaining"`
+
+	// Error describes why the destroy or verification failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// Failure records a step of the benchmark that failed
+type Failure struct {
+	// Phase is the step that failed, e.g. "build" or "command"
+	Phase string `json:"phase"`
+
+	// Iteration is the iteration that failed, or 0 for steps run once per reference
+	Iteration int `json:"iteration,omitempty"`
+
+	Error string `json:"error"`
+
+	// Log is the path of the failing step's log file, relative to the run directory
+	Log string `json:"log,omitempty"`
+
+	// LogTail holds the last lines of the log file
+	LogTail string `json:"log_tail,omitempty"`
+}
+
+// RegressionDetails records a result that is significantly slower than the baseline reference's
+type RegressionDetails struct {
+	Baseline string `json:"baseline"`
+
+	// Delta is the percentage change of the median duration from the baseline
+	Delta float64 `json:"delta"`
+
+	// P is the p-value of the Mann-Whitney U test
+	P float64 `json:"p"`
+}
+
+// Phase records the timing and resource usage of one step of a benchmark, such as building the provider or running
+// the measured command
+type Phase struct {
+	// Name identifies the step, e.g. "checkout", "build", "prepare" or "command"
+	Name string `json:"name"`
+
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Duration is the wall clock duration in seconds
+	Duration float64 `json:"duration"`
+
+	// UserCPU is the user CPU time in seconds used by the processes run during the step
+	UserCPU float64 `json:"user_cpu,omitempty"`
+
+	// SystemCPU is the system CPU time in seconds used by the processes run during the step
+	SystemCPU float64 `json:"system_cpu,omitempty"`
+
+	// PeakRSSBytes is the largest resident set size of the processes run during the step, where the platform reports it
+	PeakRSSBytes int64 `json:"peak_rss_bytes,omitempty"`
+
+	// APICalls is the number of HTTP requests the provider logged during the measured command, which it only does
+	// when TF_LOG is DEBUG or TRACE
+	APICalls int64 `json:"api_calls,omitempty"`
+}
+
+// Sample holds the phases of a single iteration of the measured command
+type Sample struct {
+	Iteration int     `json:"iteration"`
+	Phases    []Phase `json:"phases"`
+
+	// Resources holds the seconds spent refreshing, creating, updating or deleting resources, keyed by resource type,
+	// when ResourceTimings is set
+	Resources map[string]float64 `json:"resources,omitempty"`
+
+	// Log is the path of the measured command's log file in this iteration, relative to the run directory
+	Log string `json:"log,omitempty"`
 }
 
 // PlanDetails stores details about each Terraform plan execution
 type PlanDetails struct {
-	Version  string  `json:"version"`
+	Version string `json:"version"`
+
+	// Duration is the mean duration in seconds of the measured command across iterations
 	Duration float64 `json:"duration"`
+
+	// Statistics describes the duration of the measured command across iterations
+	Statistics *Statistics `json:"statistics,omitempty"`
+
+	// SHA is the commit the reference resolved to
+	SHA string `json:"sha,omitempty"`
+
+	// Setup holds the phases run once for the reference before it was measured, e.g. checking out and building the
+	// provider. They are shared by every configuration and matrix cell of the reference.
+	Setup []Phase `json:"setup,omitempty"`
+
+	// Samples holds the phases of each iteration
+	Samples []Sample `json:"samples,omitempty"`
+
+	// Command is the full Terraform command line that was measured, with the values of -var flags redacted
+	Command []string `json:"command,omitempty"`
+
+	// Log is the path of the measured command's log file in the first iteration, relative to the run directory. Each
+	// sample records the log of its own iteration.
+	Log string `json:"log,omitempty"`
+
+	// Config is the name of the Terraform configuration the command was run against, when TfConfigs is used
+	Config string `json:"config,omitempty"`
+
+	// Cell holds the matrix axis values the command was run with, keyed by axis name
+	Cell map[string]string `json:"cell,omitempty"`
+
+	// Idempotency records the plan run after the apply, when CheckIdempotency is set
+	Idempotency *IdempotencyDetails `json:"idempotency,omitempty"`
+
+	// PlanDifferences describes how the plan differs from the baseline reference's, when CheckPlanEquivalence is set
+	PlanDifferences []string `json:"plan_differences,omitempty"`
+
+	// Failure records the step that failed, when ContinueOnFailure is set
+	Failure *Failure `json:"failure,omitempty"`
+
+	// Regression records a slowdown from the baseline reference greater than MaxRegressionPercent
+	Regression *RegressionDetails `json:"regression,omitempty"`
+
+	// resourceChanges holds the planned resource changes keyed by address, when CheckPlanEquivalence is set
+	resourceChanges map[string]resourceChange
 }
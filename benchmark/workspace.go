@@ -0,0 +1,351 @@
+package benchmark
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	localStateFileName         = "terraform.tfstate"
+	backendOverrideFileName    = "benchmark_backend_override.tf"
+	backendOverrideFileContent = `terraform {
+  backend "local" {
+    path = %q
+  }
+}
+`
+)
+
+// workspace is the directory terraform commands for a single configuration are run in
+type workspace struct {
+	config TfConfig
+
+	// dir is the directory terraform commands are run in
+	dir string
+
+	// logsDir is the directory the configuration's log files are written to
+	logsDir string
+
+	// env holds environment variables set for every terraform command run in the workspace
+	env []string
+
+	// temporary is true when dir is a copy that is removed once the workspace is released
+	temporary bool
+
+	// base is the workspace a temporary workspace was copied from
+	base *workspace
+
+	// sourceDir is the absolute path of the directory a temporary workspace's configuration was copied from
+	sourceDir string
+
+	// logName is the base name of the logs of a temporary workspace created for a reference, e.g. main or seed_main
+	logName string
+
+	// syncState is true when a temporary workspace shares its base's local state, which is copied in when the
+	// workspace is created and back when it is released. Otherwise the workspace owns its state.
+	syncState bool
+
+	// snapshot is the path of the seeded state restored before every measured command (empty when not seeding)
+	snapshot string
+
+	// seed is true for the workspace the seed reference was applied in, which owns the seeded infrastructure
+	seed bool
+
+	// initialised is true once terraform init has run in the workspace
+	initialised bool
+
+	// cleanedUp is true once the final destroy has run in the workspace
+	cleanedUp bool
+}
+
+// tfConfigs returns the configurations to benchmark, treating TfConfigDir as a single unnamed configuration
+func (b *Benchmark) tfConfigs() []TfConfig {
+	if len(b.TfConfigs) > 0 {
+		return b.TfConfigs
+	}
+	return []TfConfig{{Dir: b.TfConfigDir}}
+}
+
+// validateTfConfigs checks that exactly one of TfConfigDir or TfConfigs is set and that every configuration is uniquely
+// named and has its own directory. Names are used as directory names in the run directory, so they cannot contain
+// path separators or be "." or "..".
+func (b *Benchmark) validateTfConfigs() error {
+	if b.TfConfigDir == "" && len(b.TfConfigs) == 0 {
+		return errors.New("terraform config directory is required")
+	}
+	if b.TfConfigDir != "" && len(b.TfConfigs) > 0 {
+		return errors.New("only one of TfConfigDir or TfConfigs can be set")
+	}
+
+	names := make(map[string]bool)
+	dirs := make(map[string]string)
+	for _, config := range b.tfConfigs() {
+		if len(b.TfConfigs) > 0 {
+			if config.Name == "" {
+				return errors.New("terraform config name is required")
+			}
+			if strings.ContainsAny(config.Name, `/\`) || config.Name == "." || config.Name == ".." {
+				return fmt.Errorf("terraform config name %s cannot be used as a directory name", config.Name)
+			}
+			if names[config.Name] {
+				return fmt.Errorf("terraform config %s is defined more than once", config.Name)
+			}
+			names[config.Name] = true
+		}
+
+		if config.Dir == "" {
+			return fmt.Errorf("terraform config directory is required for %s", config.Name)
+		}
+		if _, err := os.Stat(config.Dir); os.IsNotExist(err) {
+			return fmt.Errorf("terraform config directory does not exist at %s", config.Dir)
+		}
+
+		// Configurations sharing a directory would share .terraform and state
+		dir, err := filepath.Abs(config.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve terraform config directory %s: %w", config.Dir, err)
+		}
+		if other, ok := dirs[dir]; ok {
+			return fmt.Errorf("terraform configs %s and %s share the directory %s", other, config.Name, config.Dir)
+		}
+		dirs[dir] = config.Name
+
+		if err := b.validateVarFiles(config.Dir, ""); err != nil {
+			return err
+		}
+		if b.Isolation != IsolationNone || len(b.ReferenceOverrides) > 0 {
+			if err := validateModuleSources(config.Dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// moduleSourcePattern matches a local module source outside the directory of the configuration using it
+var moduleSourcePattern = regexp.MustCompile(`(?m)^\s*source\s*=\s*"(\.\.[/\\][^"]*)"`)
+
+// validateModuleSources checks that a configuration copied into temporary workspaces does not use local modules from
+// outside its directory, e.g. ../modules/network, as they would not be found next to the copy
+func validateModuleSources(configDir string) error {
+	files, err := filepath.Glob(filepath.Join(configDir, "*.tf"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read terraform config file %s: %w", file, err)
+		}
+		if match := moduleSourcePattern.FindSubmatch(content); match != nil {
+			return fmt.Errorf("terraform config %s uses the module %s from outside its directory, which is not copied into temporary workspaces", file, match[1])
+		}
+	}
+	return nil
+}
+
+// validateVarFiles checks that every var-file exists, resolving relative paths against the configuration directory
+// terraform is run in. Files in overlayDir, if set, are copied into that directory and so are found there too.
+func (b *Benchmark) validateVarFiles(configDir, overlayDir string) error {
+	for _, varFile := range b.VarFiles {
+		if filepath.IsAbs(varFile) {
+			if _, err := os.Stat(varFile); os.IsNotExist(err) {
+				return fmt.Errorf("var file does not exist at %s", varFile)
+			}
+			continue
+		}
+
+		found := false
+		for _, dir := range []string{configDir, overlayDir} {
+			if dir == "" {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(dir, varFile)); err == nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("var file does not exist at %s", filepath.Join(configDir, varFile))
+		}
+	}
+	return nil
+}
+
+// configLogsDir returns the directory log files for a configuration are written to
+func (b *Benchmark) configLogsDir(config TfConfig) string {
+	if config.Name == "" {
+		return b.logsDir
+	}
+	return filepath.Join(b.logsDir, config.Name)
+}
+
+// newWorkspace returns the workspace for a configuration. Named configurations get their own terraform data directory
+// so that an inherited TF_DATA_DIR cannot make them share providers and modules.
+func (b *Benchmark) newWorkspace(config TfConfig) (*workspace, error) {
+	ws := &workspace{
+		config:  config,
+		dir:     config.Dir,
+		logsDir: b.configLogsDir(config),
+	}
+
+	if config.Name != "" {
+		dir, err := filepath.Abs(config.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve terraform config directory %s: %w", config.Dir, err)
+		}
+		ws.env = append(ws.env, "TF_DATA_DIR="+filepath.Join(dir, ".terraform"))
+	}
+
+	return ws, nil
+}
+
+// logFilePath returns the path of a log file in the workspace's log directory
+func (ws *workspace) logFilePath(name string) string {
+	return filepath.Join(ws.logsDir, name)
+}
+
+// destroyLogFilePath returns the log of a destroy tearing down everything in the workspace, named after the reference
+// the workspace was created for, if any
+func (ws *workspace) destroyLogFilePath() string {
+	if ws.logName == "" {
+		return ws.logFilePath(destroyLogFileName)
+	}
+	return ws.logFilePath(logFileName(ws.logName + "_destroy"))
+}
+
+// varFilePath returns the path a var-file is passed to terraform with. Relative var-files outside the configuration
+// directory, such as ../shared.tfvars, are not copied into a temporary workspace, so they are resolved against the
+// directory it was copied from. Others are found in the copy, which may have replaced them with overlay files.
+func (ws *workspace) varFilePath(varFile string) string {
+	if !ws.temporary || filepath.IsAbs(varFile) || filepath.IsLocal(varFile) {
+		return varFile
+	}
+	return filepath.Join(ws.sourceDir, varFile)
+}
+
+// String returns the name of the configuration, or its directory when unnamed
+func (ws *workspace) String() string {
+	if ws.config.Name == "" {
+		return ws.dir
+	}
+	return fmt.Sprintf("%s (%s)", ws.config.Name, ws.dir)
+}
+
+// newTemporaryWorkspace copies the base workspace's configuration (or the override's ConfigDir) into a temporary
+// directory, applies the override's overlay files and initialises it. When syncState is set the base's local state is
+// copied in, and copied back on release. When isolation is enabled the copy is forced onto a local backend inside it.
+// Its logs are named after logName, see referenceLogName, or shared by the configuration when it is empty.
+func (b *Benchmark) newTemporaryWorkspace(base *workspace, logName string, override *ReferenceOverride, syncState bool) (*workspace, error) {
+	dir, err := os.MkdirTemp("", "terraform-provider-benchmark-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary workspace: %w", err)
+	}
+
+	ws := &workspace{
+		config:    base.config,
+		dir:       dir,
+		logsDir:   base.logsDir,
+		env:       []string{"TF_DATA_DIR=" + filepath.Join(dir, ".terraform")},
+		temporary: true,
+		base:      base,
+		logName:   logName,
+		syncState: syncState,
+		snapshot:  base.snapshot,
+	}
+
+	if err := b.populateTemporaryWorkspace(ws, override); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	initLogFilePath := ws.logFilePath(initLogFileName)
+	if logName != "" {
+		initLogFilePath = ws.logFilePath(logFileName(logName + "_init"))
+	}
+	if err := b.initialiseTerraform(ws, initLogFilePath); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("terraform init failed for temporary workspace of %s: %v", base, err)
+	}
+
+	return ws, nil
+}
+
+// populateTemporaryWorkspace copies the configuration, state and overlay files into a temporary workspace
+func (b *Benchmark) populateTemporaryWorkspace(ws *workspace, override *ReferenceOverride) error {
+	source := ws.base.dir
+	if override != nil {
+		b.logMessage(LogLevelInfo, "Applying override %s in temporary workspace %s", override.Pattern, ws.dir)
+		if override.ConfigDir != "" {
+			source = override.ConfigDir
+		}
+	}
+
+	sourceDir, err := filepath.Abs(source)
+	if err != nil {
+		return fmt.Errorf("failed to resolve terraform config directory %s: %w", source, err)
+	}
+	ws.sourceDir = sourceDir
+
+	b.logMessage(LogLevelDebug, "Copying terraform config from %s to %s", source, ws.dir)
+	if err := copyDir(source, ws.dir, terraformStateFiles); err != nil {
+		return fmt.Errorf("failed to copy terraform config from %s: %w", source, err)
+	}
+
+	if ws.syncState {
+		if err := copyFileIfExists(filepath.Join(ws.base.dir, localStateFileName), filepath.Join(ws.dir, localStateFileName)); err != nil {
+			return fmt.Errorf("failed to copy terraform state: %w", err)
+		}
+	}
+
+	if override != nil && override.OverlayDir != "" {
+		if err := copyDir(override.OverlayDir, ws.dir, nil); err != nil {
+			return fmt.Errorf("failed to copy overlay files from %s: %w", override.OverlayDir, err)
+		}
+	}
+
+	if b.Isolation != IsolationNone {
+		backend := fmt.Sprintf(backendOverrideFileContent, filepath.Join(ws.dir, localStateFileName))
+		if err := os.WriteFile(filepath.Join(ws.dir, backendOverrideFileName), []byte(backend), 0644); err != nil {
+			return fmt.Errorf("failed to write backend override: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// releaseWorkspace removes a temporary workspace. State shared with the base workspace is copied back first, while a
+// workspace that owns its state has anything the benchmark created destroyed. Workspaces restored from a seed snapshot
+// leave the seeded infrastructure to the seed workspace. If a destroy fails the workspace is kept so its state is not
+// lost. Workspaces that are not temporary are left untouched.
+func (b *Benchmark) releaseWorkspace(ws *workspace) error {
+	if !ws.temporary {
+		return nil
+	}
+
+	if ws.syncState {
+		if err := copyFileIfExists(filepath.Join(ws.dir, localStateFileName), filepath.Join(ws.base.dir, localStateFileName)); err != nil {
+			return fmt.Errorf("failed to copy terraform state back to %s: %w", ws.base.dir, err)
+		}
+	} else if !ws.cleanedUp && (ws.seed || (ws.snapshot == "" && b.TfCommand.spec().mutatesState)) {
+		teardown := func(ws *workspace) error {
+			return appendLogTail(b.destroy(ws, ws.destroyLogFilePath()), ws.destroyLogFilePath())
+		}
+		if b.FinalDestroy {
+			teardown = func(ws *workspace) error {
+				_, err := b.cleanUp(ws)
+				return err
+			}
+		}
+		if err := teardown(ws); err != nil {
+			return fmt.Errorf("failed to tear down temporary workspace, it has been kept at %s: %w", ws.dir, err)
+		}
+	}
+
+	b.logMessage(LogLevelDebug, "Removing temporary workspace %s", ws.dir)
+	return os.RemoveAll(ws.dir)
+}
@@ -0,0 +1,307 @@
+package benchmark
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBenchmark_validateTfConfigs(t *testing.T) {
+	tempDir := t.TempDir()
+	small := filepath.Join(tempDir, "small")
+	large := filepath.Join(tempDir, "large")
+	modular := filepath.Join(tempDir, "modular")
+	for _, dir := range []string{small, large, modular} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatalf("Failed to create config directory: %v", err)
+		}
+	}
+	module := "module \"network\" {\n  source = \"../modules/network\"\n}\n"
+	if err := os.WriteFile(filepath.Join(modular, "main.tf"), []byte(module), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		benchmark *Benchmark
+		errMsg    string
+	}{
+		{
+			name:      "single directory",
+			benchmark: &Benchmark{TfConfigDir: small},
+		},
+		{
+			name:      "named configurations",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "small", Dir: small}, {Name: "large", Dir: large}}},
+		},
+		{
+			name:      "neither set",
+			benchmark: &Benchmark{},
+			errMsg:    "terraform config directory is required",
+		},
+		{
+			name:      "both set",
+			benchmark: &Benchmark{TfConfigDir: small, TfConfigs: []TfConfig{{Name: "large", Dir: large}}},
+			errMsg:    "only one of TfConfigDir or TfConfigs",
+		},
+		{
+			name:      "missing name",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Dir: small}}},
+			errMsg:    "terraform config name is required",
+		},
+		{
+			name:      "duplicate name",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "a", Dir: small}, {Name: "a", Dir: large}}},
+			errMsg:    "defined more than once",
+		},
+		{
+			name:      "name with a path separator",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "a/b", Dir: small}}},
+			errMsg:    "terraform config name a/b cannot be used as a directory name",
+		},
+		{
+			name:      "name escaping the run directory",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "..", Dir: small}}},
+			errMsg:    "terraform config name .. cannot be used as a directory name",
+		},
+		{
+			name:      "shared directory",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "a", Dir: small}, {Name: "b", Dir: small + "/"}}},
+			errMsg:    "share the directory",
+		},
+		{
+			name:      "missing directory",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "a", Dir: filepath.Join(tempDir, "missing")}}},
+			errMsg:    "terraform config directory does not exist at",
+		},
+		{
+			name:      "var file missing from one configuration",
+			benchmark: &Benchmark{TfConfigs: []TfConfig{{Name: "a", Dir: small}}, VarFiles: []string{"vars.tfvars"}},
+			errMsg:    "var file does not exist at " + filepath.Join(small, "vars.tfvars"),
+		},
+		{
+			name:      "module outside the directory used in place",
+			benchmark: &Benchmark{TfConfigDir: modular},
+		},
+		{
+			name:      "module outside the directory of an isolated configuration",
+			benchmark: &Benchmark{TfConfigDir: modular, Isolation: IsolationPerRun},
+			errMsg:    "uses the module ../modules/network from outside its directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.benchmark.validateTfConfigs()
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("validateTfConfigs() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("validateTfConfigs() error = %v, want %v", err, tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestBenchmark_newWorkspace(t *testing.T) {
+	b := &Benchmark{logsDir: "/test/logs"}
+
+	ws, err := b.newWorkspace(TfConfig{Dir: "/test/config"})
+	if err != nil {
+		t.Fatalf("newWorkspace() error = %v", err)
+	}
+	if ws.logsDir != "/test/logs" || len(ws.env) != 0 {
+		t.Errorf("unnamed workspace = %+v, want logs in /test/logs and no extra environment", ws)
+	}
+
+	ws, err = b.newWorkspace(TfConfig{Name: "large", Dir: "/test/large"})
+	if err != nil {
+		t.Fatalf("newWorkspace() error = %v", err)
+	}
+	if ws.logsDir != filepath.Join("/test/logs", "large") {
+		t.Errorf("logsDir = %v, want %v", ws.logsDir, filepath.Join("/test/logs", "large"))
+	}
+	if len(ws.env) != 1 || ws.env[0] != "TF_DATA_DIR="+filepath.Join("/test/large", ".terraform") {
+		t.Errorf("env = %v, want a TF_DATA_DIR inside the configuration directory", ws.env)
+	}
+}
+
+func TestBenchmark_createOutputDirectoriesWithTfConfigs(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	b := &Benchmark{
+		OutputDir:  "out",
+		References: []string{"main"},
+		TfConfigs:  []TfConfig{{Name: "small", Dir: "."}, {Name: "large", Dir: "."}},
+	}
+	b.configureOutputPaths()
+
+	if err := b.createOutputDirectories(); err != nil {
+		t.Fatalf("createOutputDirectories() error = %v", err)
+	}
+
+	for _, name := range []string{"small", "large"} {
+		entries, err := os.ReadDir(filepath.Join(b.logsDir, name))
+		if err != nil || len(entries) != 0 {
+			t.Errorf("expected an empty logs directory for %s, got %d entries (%v)", name, len(entries), err)
+		}
+	}
+}
+
+func TestWorkspace_varFilePath(t *testing.T) {
+	baseDir := t.TempDir()
+	ws := &workspace{dir: t.TempDir(), base: &workspace{dir: baseDir}, temporary: true}
+	if err := (&Benchmark{}).populateTemporaryWorkspace(ws, nil); err != nil {
+		t.Fatalf("populateTemporaryWorkspace() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		ws       *workspace
+		varFile  string
+		expected string
+	}{
+		{name: "in place", ws: &workspace{dir: baseDir}, varFile: "../shared.tfvars", expected: "../shared.tfvars"},
+		{name: "copied with the configuration", ws: ws, varFile: "envs/prod.tfvars", expected: "envs/prod.tfvars"},
+		{name: "outside the copy", ws: ws, varFile: "../shared.tfvars", expected: filepath.Join(filepath.Dir(baseDir), "shared.tfvars")},
+		{name: "absolute", ws: ws, varFile: filepath.Join(baseDir, "prod.tfvars"), expected: filepath.Join(baseDir, "prod.tfvars")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if path := tt.ws.varFilePath(tt.varFile); path != tt.expected {
+				t.Errorf("varFilePath() = %v, want %v", path, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBenchmark_populateTemporaryWorkspace(t *testing.T) {
+	baseDir := t.TempDir()
+	overlayDir := t.TempDir()
+	files := map[string]string{
+		filepath.Join(baseDir, "main.tf"):            "base",
+		filepath.Join(baseDir, "users.tf"):           "base users",
+		filepath.Join(baseDir, localStateFileName):   "state",
+		filepath.Join(overlayDir, "users.tf"):        "overlay users",
+		filepath.Join(overlayDir, "compat_extra.tf"): "overlay extra",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		isolation IsolationMode
+		syncState bool
+		expected  map[string]string
+	}{
+		{
+			name:      "override sharing state",
+			isolation: IsolationNone,
+			syncState: true,
+			expected: map[string]string{
+				"main.tf":          "base",
+				"users.tf":         "overlay users",
+				"compat_extra.tf":  "overlay extra",
+				localStateFileName: "state",
+			},
+		},
+		{
+			name:      "isolated",
+			isolation: IsolationPerReference,
+			expected: map[string]string{
+				"main.tf":          "base",
+				"users.tf":         "overlay users",
+				localStateFileName: "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Benchmark{Isolation: tt.isolation}
+			ws := &workspace{dir: t.TempDir(), base: &workspace{dir: baseDir}, temporary: true, syncState: tt.syncState}
+
+			if err := b.populateTemporaryWorkspace(ws, &ReferenceOverride{Pattern: "v1.4*", OverlayDir: overlayDir}); err != nil {
+				t.Fatalf("populateTemporaryWorkspace() error = %v", err)
+			}
+
+			for name, expected := range tt.expected {
+				content, err := os.ReadFile(filepath.Join(ws.dir, name))
+				if expected == "" {
+					if !os.IsNotExist(err) {
+						t.Errorf("%s should not exist", name)
+					}
+					continue
+				}
+				if string(content) != expected {
+					t.Errorf("%s = %q, want %q", name, content, expected)
+				}
+			}
+
+			backend, err := os.ReadFile(filepath.Join(ws.dir, backendOverrideFileName))
+			if tt.isolation == IsolationNone {
+				if !os.IsNotExist(err) {
+					t.Error("backend override should only be written when isolation is enabled")
+				}
+				return
+			}
+			if !strings.Contains(string(backend), `backend "local"`) || !strings.Contains(string(backend), filepath.Join(ws.dir, localStateFileName)) {
+				t.Errorf("backend override = %s, want a local backend inside the workspace", backend)
+			}
+		})
+	}
+}
+
+func TestBenchmark_releaseWorkspace(t *testing.T) {
+	baseDir := t.TempDir()
+	tempDir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(tempDir, localStateFileName), []byte("new state"), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	b := &Benchmark{TfCommand: Plan}
+	base := &workspace{dir: baseDir}
+	if err := b.releaseWorkspace(base); err != nil {
+		t.Fatalf("releaseWorkspace() error = %v", err)
+	}
+	if _, err := os.Stat(baseDir); err != nil {
+		t.Fatalf("base workspace should not be removed: %v", err)
+	}
+
+	ws := &workspace{dir: tempDir, base: base, temporary: true, syncState: true}
+	if err := b.releaseWorkspace(ws); err != nil {
+		t.Fatalf("releaseWorkspace() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(baseDir, localStateFileName))
+	if err != nil || string(content) != "new state" {
+		t.Errorf("state was not copied back to the base workspace: %q, %v", content, err)
+	}
+	if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+		t.Error("temporary workspace was not removed")
+	}
+}
+
+func TestBenchmark_releaseWorkspaceRestoredFromSnapshot(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// The seeded infrastructure belongs to the seed workspace, so releasing a workspace restored from its snapshot must
+	// not run a destroy
+	b := &Benchmark{TfCommand: Apply, TerraformRcFilePath: "/nonexistent/terraformrc"}
+	ws := &workspace{dir: tempDir, base: &workspace{dir: t.TempDir()}, temporary: true, snapshot: "/test/seed.tfstate"}
+	if err := b.releaseWorkspace(ws); err != nil {
+		t.Fatalf("releaseWorkspace() error = %v", err)
+	}
+	if _, err := os.Stat(tempDir); !os.IsNotExist(err) {
+		t.Error("temporary workspace was not removed")
+	}
+}
@@ -0,0 +1,159 @@
+package benchmark
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResultWriter writes benchmark results in a particular format
+type ResultWriter interface {
+	// FileName returns the name of the file the results are written to in the performance directory, or an absolute
+	// path to write them elsewhere
+	FileName() string
+
+	// Write writes the results to w
+	Write(w io.Writer, results *Results) error
+}
+
+// JSONWriter writes the results document as indented JSON to data.json. It is always used.
+type JSONWriter struct{}
+
+// CSVWriter writes one comma separated row per reference, iteration and phase to data.csv
+type CSVWriter struct{}
+
+// TSVWriter writes one tab separated row per reference, iteration and phase to data.tsv
+type TSVWriter struct{}
+
+// FileName returns the name of the JSON results file
+func (JSONWriter) FileName() string {
+	return performanceDataFileName
+}
+
+// Write writes the results document as indented JSON
+func (JSONWriter) Write(w io.Writer, results *Results) error {
+	jsonData, err := json.MarshalIndent(results, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
+	}
+	_, err = w.Write(jsonData)
+	return err
+}
+
+// FileName returns the name of the CSV results file
+func (CSVWriter) FileName() string {
+	return "data.csv"
+}
+
+// Write writes the results as comma separated values
+func (CSVWriter) Write(w io.Writer, results *Results) error {
+	return writeDelimited(w, results.Results, ',')
+}
+
+// FileName returns the name of the TSV results file
+func (TSVWriter) FileName() string {
+	return "data.tsv"
+}
+
+// Write writes the results as tab separated values
+func (TSVWriter) Write(w io.Writer, results *Results) error {
+	return writeDelimited(w, results.Results, '\t')
+}
+
+// delimitedHeader is the header row of the CSV and TSV results
+var delimitedHeader = []string{
+	"reference", "sha", "config", "cell", "iteration", "phase", "start", "end",
+	"duration_seconds", "user_cpu_seconds", "system_cpu_seconds", "peak_rss_bytes", "api_calls",
+}
+
+// writeDelimited writes one row per reference, iteration and phase. Setup phases, which run once per reference, are
+// written with iteration 0.
+func writeDelimited(w io.Writer, data []PlanDetails, comma rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+
+	if err := writer.Write(delimitedHeader); err != nil {
+		return err
+	}
+
+	for _, plan := range data {
+		for _, phase := range plan.Setup {
+			if err := writer.Write(delimitedRow(plan, 0, phase)); err != nil {
+				return err
+			}
+		}
+		for _, sample := range plan.Samples {
+			for _, phase := range sample.Phases {
+				if err := writer.Write(delimitedRow(plan, sample.Iteration, phase)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// delimitedRow returns the CSV or TSV row for a phase
+func delimitedRow(plan PlanDetails, iteration int, phase Phase) []string {
+	return []string{
+		plan.Version,
+		plan.SHA,
+		plan.Config,
+		formatCell(plan.Cell),
+		strconv.Itoa(iteration),
+		phase.Name,
+		phase.Start.Format(time.RFC3339Nano),
+		phase.End.Format(time.RFC3339Nano),
+		strconv.FormatFloat(phase.Duration, 'f', -1, 64),
+		strconv.FormatFloat(phase.UserCPU, 'f', -1, 64),
+		strconv.FormatFloat(phase.SystemCPU, 'f', -1, 64),
+		strconv.FormatInt(phase.PeakRSSBytes, 10),
+		strconv.FormatInt(phase.APICalls, 10),
+	}
+}
+
+// formatCell returns the matrix cell values sorted by axis name, e.g. "log=DEBUG,parallelism=10"
+func formatCell(cell map[string]string) string {
+	names := make([]string, 0, len(cell))
+	for name := range cell {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + cell[name]
+	}
+	return strings.Join(parts, ",")
+}
+
+// writeResults writes the results with writer to a temporary file which is then renamed over path, so readers never
+// see a partially written file
+func writeResults(writer ResultWriter, path string, results *Results) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(file.Name())
+
+	if err := writer.Write(file, results); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(file.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,145 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testResults() []PlanDetails {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	return []PlanDetails{
+		{
+			Version: "main",
+			SHA:     "abc1234",
+			Config:  "small-org",
+			Cell:    map[string]string{"parallelism": "10", "log": "DEBUG"},
+			Setup: []Phase{
+				{Name: phaseCheckout, Start: start, End: start.Add(time.Second), Duration: 1},
+			},
+			Samples: []Sample{
+				{Iteration: 1, Phases: []Phase{
+					{Name: phaseCommand, Start: start, End: start.Add(2500 * time.Millisecond), Duration: 2.5, UserCPU: 1.25, SystemCPU: 0.5, PeakRSSBytes: 1024, APICalls: 42},
+				}},
+				{Iteration: 2, Phases: []Phase{
+					{Name: phaseCommand, Start: start, End: start.Add(2 * time.Second), Duration: 2},
+				}},
+			},
+		},
+	}
+}
+
+func TestWriteDelimited(t *testing.T) {
+	tests := []struct {
+		name   string
+		writer ResultWriter
+		comma  rune
+	}{
+		{name: "csv", writer: CSVWriter{}, comma: ','},
+		{name: "tsv", writer: TSVWriter{}, comma: '\t'},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.writer.Write(&buf, &Results{Results: testResults()}); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+
+			reader := csv.NewReader(&buf)
+			reader.Comma = tt.comma
+			rows, err := reader.ReadAll()
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+
+			if len(rows) != 4 {
+				t.Fatalf("expected header and 3 rows, got %d rows", len(rows))
+			}
+			if strings.Join(rows[0], ",") != strings.Join(delimitedHeader, ",") {
+				t.Errorf("header = %v, want %v", rows[0], delimitedHeader)
+			}
+
+			expected := []string{"main", "abc1234", "small-org", "log=DEBUG,parallelism=10", "1", "command",
+				"2024-05-01T12:00:00Z", "2024-05-01T12:00:02.5Z", "2.5", "1.25", "0.5", "1024", "42"}
+			if strings.Join(rows[2], "|") != strings.Join(expected, "|") {
+				t.Errorf("row = %v, want %v", rows[2], expected)
+			}
+
+			if rows[1][4] != "0" || rows[1][5] != phaseCheckout {
+				t.Errorf("expected setup phase with iteration 0, got %v", rows[1])
+			}
+			if rows[3][4] != "2" {
+				t.Errorf("expected second iteration, got %v", rows[3])
+			}
+		})
+	}
+}
+
+func TestBenchmark_writeDataToFile_ResultWriters(t *testing.T) {
+	tempDir := t.TempDir()
+	b := &Benchmark{
+		LogLevel:       LogLevelQuiet,
+		performanceDir: tempDir,
+		ResultWriters:  []ResultWriter{CSVWriter{}, TSVWriter{}},
+	}
+
+	if err := b.writeDataToFile(&Results{Results: testResults()}); err != nil {
+		t.Fatalf("writeDataToFile() error = %v", err)
+	}
+
+	for _, name := range []string{"data.json", "data.csv", "data.tsv"} {
+		if _, err := os.Stat(filepath.Join(tempDir, name)); err != nil {
+			t.Errorf("expected %s to be written: %v", name, err)
+		}
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 3 {
+		t.Errorf("expected only the result files, got %d entries", len(entries))
+	}
+}
+
+func TestBenchmark_runPhase(t *testing.T) {
+	b := &Benchmark{}
+
+	phase, err := b.runPhase(phaseCommand, func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runPhase() error = %v", err)
+	}
+
+	if phase.Name != phaseCommand {
+		t.Errorf("Name = %v, want %v", phase.Name, phaseCommand)
+	}
+	if phase.Duration < 0.01 || !phase.End.After(phase.Start) {
+		t.Errorf("unexpected timing: %+v", phase)
+	}
+	if b.currentPhase != nil {
+		t.Error("expected current phase to be cleared")
+	}
+}
+
+func TestBenchmark_iterations(t *testing.T) {
+	tests := []struct {
+		iterations int
+		expected   int
+	}{
+		{iterations: 0, expected: 1},
+		{iterations: 1, expected: 1},
+		{iterations: 5, expected: 5},
+	}
+
+	for _, tt := range tests {
+		b := &Benchmark{Iterations: tt.iterations}
+		if result := b.iterations(); result != tt.expected {
+			t.Errorf("iterations() with Iterations=%d = %d, want %d", tt.iterations, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliecon/terraform-provider-benchmark/benchmark"
+)
+
+// changepointsCommand reports the commits at which the duration of a scenario shifted
+func changepointsCommand(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("changepoints", stderr)
+	dir := flags.String("dir", filepath.Join("output", "history"), "history directory")
+	ref := flags.String("ref", "", "only analyse results for this reference, e.g. the main branch")
+	scenario := flags.String("scenario", "", `only analyse results for this scenario, e.g. "small-org [parallelism=10]"`)
+	command := flags.String("command", "", `only analyse results for this command, e.g. "Plan"`)
+	since := flags.String("since", "", "only analyse runs started at or after this date or time")
+	until := flags.String("until", "", "only analyse runs started before this date or time")
+	minSegment := flags.Int("min-segment", 3, "fewest commits either side of a change point")
+	permutations := flags.Int("permutations", 199, "permutations used to test the significance of a change point")
+	significance := flags.Float64("significance", 0.05, "p-value below which a change point is reported")
+	asJSON := flags.Bool("json", false, "write the change points as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := benchmark.HistoryQuery{Ref: *ref, Scenario: *scenario, Command: *command}
+	var err error
+	if query.Since, err = parseTime(*since); err != nil {
+		return err
+	}
+	if query.Until, err = parseTime(*until); err != nil {
+		return err
+	}
+
+	entries, err := benchmark.OpenHistory(*dir).Query(query)
+	if err != nil {
+		return err
+	}
+
+	changePoints := benchmark.DetectChangePoints(entries, benchmark.ChangePointOptions{
+		MinSegment:   *minSegment,
+		Permutations: *permutations,
+		Significance: *significance,
+	})
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "    ")
+		if changePoints == nil {
+			changePoints = []benchmark.ChangePoint{}
+		}
+		return encoder.Encode(changePoints)
+	}
+	if len(changePoints) == 0 {
+		fmt.Fprintln(stdout, "No change points found")
+		return nil
+	}
+	return writeChangePointTable(stdout, changePoints)
+}
+
+// writeChangePointTable writes a row per change point with the median durations either side of it
+func writeChangePointTable(w io.Writer, changePoints []benchmark.ChangePoint) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COMMAND\tSCENARIO\tTIME\tREF\tSHA\tBEFORE (s)\tAFTER (s)\tCHANGE\tP\tDIRECTION")
+
+	for _, changePoint := range changePoints {
+		scenario := changePoint.Scenario
+		if scenario == "" {
+			scenario = "-"
+		}
+
+		direction := "improvement"
+		if changePoint.Regression() {
+			direction = "regression"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%.2f\t%+.1f%%\t%.3f\t%s\n",
+			changePoint.Command, scenario, changePoint.Time.Local().Format(time.DateTime), changePoint.Ref, shortSHA(changePoint.SHA),
+			changePoint.Before, changePoint.After, changePoint.Delta, changePoint.P, direction)
+	}
+	return tw.Flush()
+}
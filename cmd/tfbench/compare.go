@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/charliecon/terraform-provider-benchmark/benchmark"
+)
+
+// compareCommand compares the results of two runs of the benchmark
+func compareCommand(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("compare", stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tfbench compare [flags] <old data.json> <new data.json>")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", benchmark.ComparisonText, "output format: text, markdown or json")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return fmt.Errorf("compare takes 2 results files, got %d", flags.NArg())
+	}
+
+	old, err := benchmark.LoadResults(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	new, err := benchmark.LoadResults(flags.Arg(1))
+	if err != nil {
+		return err
+	}
+	return benchmark.WriteComparison(stdout, old, new, *format)
+}
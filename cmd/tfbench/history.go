@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charliecon/terraform-provider-benchmark/benchmark"
+)
+
+// historyCommand lists the results in the history matching the flags
+func historyCommand(args []string, stdout, stderr io.Writer) error {
+	flags := newFlagSet("history", stderr)
+	dir := flags.String("dir", filepath.Join("output", "history"), "history directory")
+	ref := flags.String("ref", "", "only list results for this reference")
+	sha := flags.String("sha", "", "only list results for commits starting with this SHA")
+	scenario := flags.String("scenario", "", `only list results for this scenario, e.g. "small-org [parallelism=10]"`)
+	command := flags.String("command", "", `only list results for this command, e.g. "Plan"`)
+	since := flags.String("since", "", "only list runs started at or after this date or time")
+	until := flags.String("until", "", "only list runs started before this date or time")
+	asJSON := flags.Bool("json", false, "write the entries as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	query := benchmark.HistoryQuery{Ref: *ref, SHA: *sha, Scenario: *scenario, Command: *command}
+	var err error
+	if query.Since, err = parseTime(*since); err != nil {
+		return err
+	}
+	if query.Until, err = parseTime(*until); err != nil {
+		return err
+	}
+
+	entries, err := benchmark.OpenHistory(*dir).Query(query)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "    ")
+		if entries == nil {
+			entries = []benchmark.HistoryEntry{}
+		}
+		return encoder.Encode(entries)
+	}
+	return writeHistoryTable(stdout, entries)
+}
+
+// writeHistoryTable writes a row per entry with the median duration of the measured command
+func writeHistoryTable(w io.Writer, entries []benchmark.HistoryEntry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tRUN\tREF\tSHA\tSCENARIO\tN\tMEDIAN (s)\tSTDDEV (s)\tSTATUS")
+
+	for _, entry := range entries {
+		result := entry.Result
+		n, median, stddev := 1, result.Duration, 0.0
+		if result.Statistics != nil {
+			n, median, stddev = result.Statistics.N, result.Statistics.Median, result.Statistics.StdDev
+		}
+
+		scenario := entry.Scenario()
+		if scenario == "" {
+			scenario = "-"
+		}
+
+		status := "ok"
+		if result.Failed() {
+			status = "failed"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%.2f\t%.2f\t%s\n",
+			entry.Time.Local().Format(time.DateTime), entry.RunID, result.Version, shortSHA(result.SHA), scenario, n, median, stddev, status)
+	}
+	return tw.Flush()
+}
+
+// shortSHA abbreviates a commit SHA for display
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
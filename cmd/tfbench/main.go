@@ -0,0 +1,75 @@
+// Command tfbench queries and analyses the results recorded by terraform-provider-benchmark
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const usage = `Usage: tfbench <command> [flags]
+
+Commands:
+  history         List results recorded in the history
+  changepoints    Report the commits at which performance shifted
+  compare         Compare the results of two runs
+
+Run "tfbench <command> -h" for the flags of a command.
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout, os.Stderr); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(os.Stderr, "tfbench:", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run runs the command named by the first argument
+func run(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return flag.ErrHelp
+	}
+
+	switch args[0] {
+	case "history":
+		return historyCommand(args[1:], stdout, stderr)
+	case "changepoints":
+		return changepointsCommand(args[1:], stdout, stderr)
+	case "compare":
+		return compareCommand(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return nil
+	default:
+		fmt.Fprint(stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// newFlagSet returns a flag set for a command that reports errors instead of exiting
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("tfbench "+name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return flags
+}
+
+// parseTime parses a date (2006-01-02) or an RFC 3339 timestamp, returning the zero time for ""
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use YYYY-MM-DD or RFC 3339", value)
+	}
+	return t, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charliecon/terraform-provider-benchmark/benchmark"
+)
+
+func writeHistory(t *testing.T) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "history")
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	results := &benchmark.Results{RunID: "0a1b2c3d", Start: start, Results: []benchmark.PlanDetails{
+		{Version: "main", SHA: "3f1c2e9d4b5a6c7d8e9f", Command: []string{"terraform", "plan"}, Duration: 12,
+			Statistics: &benchmark.Statistics{N: 5, Median: 11.5, StdDev: 0.25}},
+		{Version: "feature", SHA: "9d8c7b6a", Command: []string{"terraform", "plan"}, Cell: map[string]string{"parallelism": "10"},
+			Duration: 10, Failure: &benchmark.Failure{Phase: "build"}},
+	}}
+	if err := benchmark.OpenHistory(dir).Append(results); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestRun_History(t *testing.T) {
+	dir := writeHistory(t)
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"history", "-dir", dir}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v, stderr: %s", err, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 rows, got:\n%s", stdout.String())
+	}
+	for _, expected := range []string{"main", "3f1c2e9d4b5a", "5", "11.50", "0.25", "ok"} {
+		if !strings.Contains(lines[1], expected) {
+			t.Errorf("expected %q in %q", expected, lines[1])
+		}
+	}
+	for _, expected := range []string{"feature", "[parallelism=10]", "10.00", "failed"} {
+		if !strings.Contains(lines[2], expected) {
+			t.Errorf("expected %q in %q", expected, lines[2])
+		}
+	}
+}
+
+func TestRun_HistoryJSON(t *testing.T) {
+	dir := writeHistory(t)
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"history", "-dir", dir, "-ref", "feature", "-since", "2024-05-01", "-json"}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	var entries []benchmark.HistoryEntry
+	if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Result.Version != "feature" || entries[0].RunID != "0a1b2c3d" {
+		t.Errorf("unexpected entries %+v", entries)
+	}
+}
+
+func TestRun_Changepoints(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	history := benchmark.OpenHistory(dir)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, duration := range []float64{10, 10.1, 9.9, 10, 10.2, 15, 15.1, 14.9, 15, 15.2} {
+		results := &benchmark.Results{RunID: fmt.Sprintf("run%d", i), Start: start.AddDate(0, 0, i), Results: []benchmark.PlanDetails{
+			{Version: "main", SHA: fmt.Sprintf("%012d", i), Command: []string{"terraform", "plan"}, Config: "small", Duration: duration},
+		}}
+		if err := history.Append(results); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"changepoints", "-dir", dir, "-ref", "main"}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v, stderr: %s", err, stderr.String())
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and 1 row, got:\n%s", stdout.String())
+	}
+	for _, expected := range []string{"Plan", "small", "000000000005", "10.00", "15.00", "+50.0%", "regression"} {
+		if !strings.Contains(lines[1], expected) {
+			t.Errorf("expected %q in %q", expected, lines[1])
+		}
+	}
+
+	stdout.Reset()
+	if err := run([]string{"changepoints", "-dir", dir, "-scenario", "large"}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if strings.TrimSpace(stdout.String()) != "No change points found" {
+		t.Errorf("unexpected output for an unknown scenario:\n%s", stdout.String())
+	}
+
+	stdout.Reset()
+	if err := run([]string{"changepoints", "-dir", dir, "-json"}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	var changePoints []benchmark.ChangePoint
+	if err := json.Unmarshal(stdout.Bytes(), &changePoints); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if len(changePoints) != 1 || changePoints[0].SHA != "000000000005" || !changePoints[0].Regression() {
+		t.Errorf("unexpected change points %+v", changePoints)
+	}
+}
+
+func TestRun_Compare(t *testing.T) {
+	dir := t.TempDir()
+	oldPath, newPath := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+
+	// A legacy document holding only the array of results, which recorded neither the commit nor the command
+	legacy := `[{"version": "main", "duration": 10}]`
+	if err := os.WriteFile(oldPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	current, err := json.Marshal(benchmark.Results{SchemaVersion: benchmark.ResultsSchemaVersion, RunID: "0a1b2c3d", Results: []benchmark.PlanDetails{
+		{Version: "main", Duration: 12, SHA: "bbbb2222", Command: []string{"terraform", "plan"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(newPath, current, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"compare", oldPath, newPath}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v, stderr: %s", err, stderr.String())
+	}
+	for _, expected := range []string{"Plan", "bbbb2222", "10.00 ± 0.00", "12.00 ± 0.00", "+20.0%"} {
+		if !strings.Contains(stdout.String(), expected) {
+			t.Errorf("expected %q in:\n%s", expected, stdout.String())
+		}
+	}
+
+	stdout.Reset()
+	if err := run([]string{"compare", "-format", "markdown", oldPath, newPath}, &stdout, &stderr); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(stdout.String(), "| ⚪ | main (`-` → `bbbb2222`) | 12.00 ± 0.00 | +20.0% | 1.000 |") {
+		t.Errorf("unexpected Markdown:\n%s", stdout.String())
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := [][]string{
+		nil,
+		{"unknown"},
+		{"history", "-since", "yesterday"},
+		{"history", "-unknown-flag"},
+		{"changepoints", "-until", "next week"},
+		{"compare", "old.json"},
+		{"compare", "missing.json", "missing.json"},
+	}
+
+	for _, args := range tests {
+		var stdout, stderr bytes.Buffer
+		if err := run(args, &stdout, &stderr); err == nil {
+			t.Errorf("run(%v) expected error", args)
+		}
+	}
+}